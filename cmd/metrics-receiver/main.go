@@ -3,18 +3,29 @@ package main
 import (
 	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/max-bytes/metrics-receiver/pkg/config"
 	"github.com/max-bytes/metrics-receiver/pkg/enrichments"
 	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/max-bytes/metrics-receiver/pkg/graphite"
 	"github.com/max-bytes/metrics-receiver/pkg/influx"
+	"github.com/max-bytes/metrics-receiver/pkg/jsonformat"
+	"github.com/max-bytes/metrics-receiver/pkg/listener"
+	"github.com/max-bytes/metrics-receiver/pkg/otlp"
+	"github.com/max-bytes/metrics-receiver/pkg/perfdata"
+	"github.com/max-bytes/metrics-receiver/pkg/prometheus"
+	"github.com/max-bytes/metrics-receiver/pkg/spool"
+	"github.com/max-bytes/metrics-receiver/pkg/statsd"
 	"github.com/max-bytes/metrics-receiver/pkg/timescale"
 	"github.com/sirupsen/logrus"
 )
@@ -32,16 +43,22 @@ var cfg = config.Configuration{
 	Port:                           80,
 	InternalMetricsCollectInterval: 60,
 	InternalMetricsFlushCycle:      1,
+	WriteBatchSize:                 5000,
+	Statsd:                         config.Statsd{FlushInterval: 10},
 	OutputsTimescale:               []config.OutputTimescale{},
 	OutputsInflux:                  []config.OutputInflux{},
 }
 
+// databases and buckets of the outputs, returned by SHOW DATABASES
+var influxDatabases = influx.NewDatabases()
+
 var internalMetrics struct {
 	incomingMetrics []general.Point
 
 	incomingMessagesCount int64
 	incomingLinesCount    int64
 	incomingBytesCount    int64
+	rejectedLinesCount    int64
 
 	internalMetricsLock sync.Mutex
 }
@@ -70,6 +87,24 @@ func main() {
 	}
 	log.SetLevel(parsedLogLevel)
 
+	if cfg.WriteBatchSize <= 0 {
+		log.Fatalf("Invalid write_batch_size in config file: %d", cfg.WriteBatchSize)
+	}
+
+	if err := initProcessors(); err != nil {
+		log.Fatalf("Invalid processors in config file: %s", err)
+	}
+
+	// the databases the outputs accept are known to clients querying SHOW DATABASES
+	var databases []string
+	for _, outputConfig := range cfg.OutputsTimescale {
+		databases = append(databases, outputConfig.Databases...)
+	}
+	for _, outputConfig := range cfg.OutputsInflux {
+		databases = append(databases, outputConfig.Databases...)
+	}
+	influxDatabases = influx.NewDatabases(databases...)
+
 	// init timescale connection pools
 	connPoolsErr := timescale.InitConnPools(cfg.OutputsTimescale)
 
@@ -116,6 +151,7 @@ func main() {
 						"received_messages": internalMetrics.incomingMessagesCount,
 						"received_lines":    internalMetrics.incomingLinesCount,
 						"received_bytes":    internalMetrics.incomingBytesCount,
+						"rejected_lines":    internalMetrics.rejectedLinesCount,
 					},
 					Timestamp: now,
 				}
@@ -124,6 +160,7 @@ func main() {
 				internalMetrics.incomingMessagesCount = 0
 				internalMetrics.incomingLinesCount = 0
 				internalMetrics.incomingBytesCount = 0
+				internalMetrics.rejectedLinesCount = 0
 
 				internalMetrics.internalMetricsLock.Unlock()
 				log.Debugf("Collected internal metrics")
@@ -136,7 +173,7 @@ func main() {
 				internalMetrics.internalMetricsLock.Lock()
 
 				// NOTE: we can't really treat critical errors different here, so we just log the error in both cases
-				criticalError, nonCriticalErrors := writeOutputs(internalMetrics.incomingMetrics)
+				criticalError, nonCriticalErrors := writeOutputs(internalMetrics.incomingMetrics, "")
 				for _, nonCriticalError := range nonCriticalErrors {
 					log.Warnf("Non-critical error writing internal metrics: %v", nonCriticalError)
 				}
@@ -154,8 +191,58 @@ func main() {
 		log.Infof("Not collecting or sending any internal metrics due to configuration")
 	}
 
+	if cfg.Graphite.TCPAddress != "" || cfg.Graphite.UDPAddress != "" {
+		err := startGraphiteListeners(cfg.Graphite)
+		if err != nil {
+			log.Fatalf("Error starting graphite listeners: %s", err)
+		}
+	}
+
+	if cfg.Statsd.UDPAddress != "" {
+		err := startStatsdListener(cfg.Statsd)
+		if err != nil {
+			log.Fatalf("Error starting statsd listener: %s", err)
+		}
+	}
+
+	for _, socketListenerConfig := range cfg.SocketListeners {
+		err := startSocketListener(socketListenerConfig)
+		if err != nil {
+			log.Fatalf("Error starting socket listener: %s", err)
+		}
+	}
+
+	for _, scrapeTargetConfig := range cfg.PrometheusScrapeTargets {
+		err := startScraper(scrapeTargetConfig)
+		if err != nil {
+			log.Fatalf("Error starting prometheus scraper: %s", err)
+		}
+	}
+
+	for _, spoolDirectoryConfig := range cfg.SpoolDirectories {
+		err := startSpoolDirectory(spoolDirectoryConfig)
+		if err != nil {
+			log.Fatalf("Error starting spool directory: %s", err)
+		}
+	}
+
+	for _, tailFileConfig := range cfg.TailFiles {
+		err := startTailFile(tailFileConfig)
+		if err != nil {
+			log.Fatalf("Error starting tail file: %s", err)
+		}
+	}
+
 	http.HandleFunc("/api/influx/v1/write", influxWriteHandler)
 	http.HandleFunc("/api/influx/v1/query", influxQueryHandler)
+	http.HandleFunc("/api/influx/v1/ping", influxPingHandler)
+	http.HandleFunc("/query", influxQueryHandler)
+	http.HandleFunc("/ping", influxPingHandler)
+	http.HandleFunc("/api/v2/write", influxV2WriteHandler)
+	http.HandleFunc("/api/prom/v1/write", prometheusWriteHandler)
+	http.HandleFunc("/v1/metrics", otlpMetricsHandler)
+	http.HandleFunc("/api/json/v1/write", jsonWriteHandler)
+	http.HandleFunc("/api/perfdata/v1/write", perfdataWriteHandler)
 	http.HandleFunc("/api/health/check", healthCheckHandler)
 	http.HandleFunc("/api/enrichment/cacheinfo", enrichmentCacheInfoHandler)
 	http.HandleFunc("/api/enrichment/cacheinfo/items", enrichmentCacheItemsInfoHandler)
@@ -176,36 +263,209 @@ func influxWriteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var reader io.ReadCloser
-	var err error
+	processLineProtocolWrite(w, r, r.URL.Query().Get("db"))
+}
+
+// POST /api/v2/write
+// compatible with influxDB v2 clients (e.g. telegraf's outputs.influxdb_v2); the "Authorization: Token ..." header sent by these
+// clients is accepted but not checked, just like the v1 endpoint does not check any credentials
+func influxV2WriteHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Infof("Receiving influx v2 write request...")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method is not supported.", http.StatusForbidden)
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		http.Error(w, "The bucket parameter is required.", http.StatusBadRequest)
+		return
+	}
+
+	processLineProtocolWrite(w, r, bucket)
+}
+
+// processLineProtocolWrite reads the (optionally gzipped) line protocol request body, parses it using the precision request parameter and writes the resulting points to the outputs
+// that accept the database/bucket db
+func processLineProtocolWrite(w http.ResponseWriter, r *http.Request, db string) {
+	precision, err := influx.ParsePrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		log.Errorf(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := openRequestBody(r)
+	if err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+	defer body.Close()
+
+	// the body is parsed as a stream and the points are written in batches, so that large requests (e.g. backfills) are never held in memory as a whole;
+	// invalid lines do not fail the whole request: the valid points are written and the invalid lines are reported back afterwards
+	var lines int
+	var criticalError error
+	var nonCriticalErrors []error
+	parseErr := influx.ParseStream(body, time.Now(), precision, cfg.WriteBatchSize, func(points []general.Point) error {
+		var batchNonCriticalErrors []error
+		criticalError, batchNonCriticalErrors = writeOutputs(points, db)
+		nonCriticalErrors = append(nonCriticalErrors, batchNonCriticalErrors...)
+		if criticalError != nil {
+			return criticalError
+		}
+		lines += len(points)
+		return nil
+	})
+	var lineErrors influx.ParseErrors
+	// a parse error other than invalid lines (e.g. an exceeded body size or line length) aborts the request at the current line,
+	// which is counted as rejected; the batches before it were already written and are counted as well
+	aborted := parseErr != nil && criticalError == nil && !errors.As(parseErr, &lineErrors)
+	rejectedLines := len(lineErrors)
+	if aborted {
+		rejectedLines++
+	}
+
+	internalMetrics.internalMetricsLock.Lock()
+	internalMetrics.incomingMessagesCount += 1
+	internalMetrics.incomingBytesCount += body.size
+	internalMetrics.incomingLinesCount += int64(lines)
+	internalMetrics.rejectedLinesCount += int64(rejectedLines)
+	internalMetrics.internalMetricsLock.Unlock()
+
+	if aborted {
+		if errors.Is(parseErr, errBodyTooLarge) {
+			writeRequestBodyError(w, parseErr)
+			return
+		}
+		log.Errorf("An error occurred while parsing the influx line protocol request: " + parseErr.Error())
+		http.Error(w, "An error occurred while parsing the influx line protocol request", http.StatusBadRequest)
+		return
+	}
+	if len(lineErrors) > 0 {
+		log.Warnf("Rejected %d invalid lines of influx line protocol request: %s", len(lineErrors), parseErr.Error())
+	}
+
+	if criticalError != nil {
+		log.Errorf(criticalError.Error())
+		http.Error(w, criticalError.Error(), http.StatusBadRequest)
+		return
+	} else {
+		for _, nonCriticalError := range nonCriticalErrors {
+			log.Warnf(nonCriticalError.Error())
+		}
+
+		if len(lineErrors) > 0 {
+			log.Printf("Partially processed influx write request; lines: %d, rejected lines: %d \n", lines, len(lineErrors))
+			writePartialWriteError(w, len(lineErrors), lineErrors)
+			return
+		}
+
+		log.Printf("Successfully processed influx write request; lines: %d \n", lines)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+var errBodyTooLarge = errors.New("Request body too large")
+
+// requestBody reads the (optionally gzip compressed) request body and fails with errBodyTooLarge once more than the
+// configured maximum body size was read; the limit applies to the decompressed body
+type requestBody struct {
+	reader io.Reader
+	closer io.Closer
+	size   int64 // number of (decompressed) bytes read so far
+}
+
+func openRequestBody(r *http.Request) (*requestBody, error) {
+	if cfg.MaxBodySize > 0 && r.ContentLength > cfg.MaxBodySize {
+		return nil, errBodyTooLarge
+	}
 
 	switch r.Header.Get("Content-Encoding") {
 	case "gzip":
-		reader, err = gzip.NewReader(r.Body)
+		reader, err := gzip.NewReader(r.Body)
 		if err != nil {
-			log.Errorf(err.Error())
-			http.Error(w, "An error ocurred while trying to read the request body!", http.StatusBadRequest)
-			return
+			return nil, err
 		}
-		defer reader.Close()
+		return &requestBody{reader: reader, closer: reader}, nil
 	default:
-		reader = r.Body
+		return &requestBody{reader: r.Body, closer: r.Body}, nil
 	}
+}
 
-	buf, err := ioutil.ReadAll(reader)
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	b.size += int64(n)
+	if cfg.MaxBodySize > 0 && b.size > cfg.MaxBodySize {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *requestBody) Close() error {
+	return b.closer.Close()
+}
 
+// readRequestBody reads the complete request body, which may be gzip compressed
+func readRequestBody(r *http.Request) ([]byte, error) {
+	body, err := openRequestBody(r)
 	if err != nil {
-		log.Errorf(err.Error())
-		http.Error(w, "An error ocurred while trying to read the request body!", http.StatusBadRequest)
+		return nil, err
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+// writeRequestBodyError responds to an error reading the request body; exceeding the maximum body size results in a 413
+func writeRequestBodyError(w http.ResponseWriter, err error) {
+	log.Errorf(err.Error())
+	if errors.Is(err, errBodyTooLarge) {
+		http.Error(w, fmt.Sprintf("The request body exceeds the maximum size of %d bytes.", cfg.MaxBodySize), http.StatusRequestEntityTooLarge)
 		return
 	}
+	http.Error(w, "An error ocurred while trying to read the request body!", http.StatusBadRequest)
+}
+
+// writePartialWriteError responds with an influx-like partial write error that lists the rejected lines and the reasons
+func writePartialWriteError(w http.ResponseWriter, rejectedLines int, lineErrors interface{}) {
+	output := map[string]interface{}{
+		"error":       fmt.Sprintf("partial write: %d lines rejected", rejectedLines),
+		"line_errors": lineErrors,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	jsonEncoder := json.NewEncoder(w)
+	jsonEncoder.Encode(output)
+}
+
+// POST /api/prom/v1/write
+func prometheusWriteHandler(w http.ResponseWriter, r *http.Request) {
 
-	requestStr := string(buf)
+	log.Infof("Receiving prometheus remote write request...")
 
-	points, parseErr := influx.Parse(requestStr, time.Now())
+	if r.Method != "POST" {
+		http.Error(w, "Method is not supported.", http.StatusForbidden)
+		return
+	}
+
+	buf, err := readRequestBody(r)
+	if err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+
+	points, parseErr := prometheus.ParseRemoteWrite(buf, cfg.MaxBodySize)
+	if errors.Is(parseErr, prometheus.ErrDecodedTooLarge) {
+		writeRequestBodyError(w, errBodyTooLarge)
+		return
+	}
 	if parseErr != nil {
-		log.Errorf("An error occurred while parsing the influx line protocol request: " + parseErr.Error())
-		http.Error(w, "An error occurred while parsing the influx line protocol request", http.StatusBadRequest)
+		log.Errorf("An error occurred while parsing the prometheus remote write request: " + parseErr.Error())
+		http.Error(w, "An error occurred while parsing the prometheus remote write request", http.StatusBadRequest)
 		return
 	}
 
@@ -215,7 +475,7 @@ func influxWriteHandler(w http.ResponseWriter, r *http.Request) {
 	internalMetrics.incomingLinesCount += int64(len(points))
 	internalMetrics.internalMetricsLock.Unlock()
 
-	criticalError, nonCriticalErrors := writeOutputs(points)
+	criticalError, nonCriticalErrors := writeOutputs(points, "")
 	if criticalError != nil {
 		log.Errorf(criticalError.Error())
 		http.Error(w, criticalError.Error(), http.StatusBadRequest)
@@ -225,18 +485,516 @@ func influxWriteHandler(w http.ResponseWriter, r *http.Request) {
 			log.Warnf(nonCriticalError.Error())
 		}
 
-		log.Printf("Successfully processed influx write request; lines: %d \n", len(points))
+		log.Printf("Successfully processed prometheus remote write request; samples: %d \n", len(points))
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-func writeOutputs(points []general.Point) (error, []error) {
+// POST /v1/metrics
+// OTLP/HTTP metrics export (the default path of the opentelemetry exporters), in the protobuf or the JSON encoding
+func otlpMetricsHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Infof("Receiving OTLP metrics request...")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method is not supported.", http.StatusForbidden)
+		return
+	}
+
+	var parse func(body []byte, currentTimestamp time.Time) ([]general.Point, error)
+	// parameters like "; charset=utf-8" are ignored
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch contentType {
+	case "application/x-protobuf":
+		parse = otlp.ParseProtobuf
+	case "application/json":
+		parse = otlp.ParseJSON
+	default:
+		http.Error(w, "Content-Type must be application/x-protobuf or application/json.", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	buf, err := readRequestBody(r)
+	if err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+
+	points, parseErr := parse(buf, time.Now())
+	if parseErr != nil {
+		log.Errorf("An error occurred while parsing the OTLP metrics request: " + parseErr.Error())
+		http.Error(w, "An error occurred while parsing the OTLP metrics request", http.StatusBadRequest)
+		return
+	}
+
+	internalMetrics.internalMetricsLock.Lock()
+	internalMetrics.incomingMessagesCount += 1
+	internalMetrics.incomingBytesCount += int64(len(buf))
+	internalMetrics.incomingLinesCount += int64(len(points))
+	internalMetrics.internalMetricsLock.Unlock()
+
+	criticalError, nonCriticalErrors := writeOutputs(points, "")
+	if criticalError != nil {
+		log.Errorf(criticalError.Error())
+		http.Error(w, criticalError.Error(), http.StatusBadRequest)
+		return
+	} else {
+		for _, nonCriticalError := range nonCriticalErrors {
+			log.Warnf(nonCriticalError.Error())
+		}
+
+		log.Printf("Successfully processed OTLP metrics request; data points: %d \n", len(points))
+
+		// respond with an empty ExportMetricsServiceResponse in the encoding of the request
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		if contentType == "application/json" {
+			w.Write([]byte("{}"))
+		}
+	}
+}
+
+// POST /api/json/v1/write
+func jsonWriteHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Infof("Receiving json write request...")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method is not supported.", http.StatusForbidden)
+		return
+	}
+
+	precision, err := influx.ParsePrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		log.Errorf(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buf, err := readRequestBody(r)
+	if err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+
+	points, parseErr := jsonformat.Parse(buf, time.Now(), precision)
+	if parseErr != nil {
+		log.Errorf("An error occurred while parsing the json write request: " + parseErr.Error())
+
+		var validationErrors jsonformat.ValidationErrors
+		if errors.As(parseErr, &validationErrors) {
+			output := map[string]interface{}{
+				"error":        fmt.Sprintf("%d points failed validation", len(validationErrors)),
+				"point_errors": validationErrors,
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			jsonEncoder := json.NewEncoder(w)
+			jsonEncoder.Encode(output)
+			return
+		}
+
+		http.Error(w, parseErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	countReceived(len(buf), len(points), 0)
+
+	if !writeRequestPoints(w, points) {
+		return
+	}
+
+	log.Printf("Successfully processed json write request; points: %d \n", len(points))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/perfdata/v1/write
+// accepts lines in the format of the nagios/icinga perfdata files, so monitoring servers can post their perfdata directly
+func perfdataWriteHandler(w http.ResponseWriter, r *http.Request) {
+
+	log.Infof("Receiving perfdata write request...")
+
+	if r.Method != "POST" {
+		http.Error(w, "Method is not supported.", http.StatusForbidden)
+		return
+	}
+
+	buf, err := readRequestBody(r)
+	if err != nil {
+		writeRequestBodyError(w, err)
+		return
+	}
+
+	points, lineErrors := perfdata.Parse(buf, time.Now())
+	lineErrorMessages := make([]string, 0, len(lineErrors))
+	for _, lineError := range lineErrors {
+		lineErrorMessages = append(lineErrorMessages, lineError.Error())
+	}
+	if len(lineErrors) > 0 {
+		log.Warnf("Rejected %d invalid lines of perfdata request: %v", len(lineErrors), lineErrorMessages)
+	}
+
+	countReceived(len(buf), len(points), len(lineErrors))
+
+	if !writeRequestPoints(w, points) {
+		return
+	}
+
+	if len(lineErrors) > 0 {
+		log.Printf("Partially processed perfdata write request; points: %d, rejected lines: %d \n", len(points), len(lineErrors))
+		writePartialWriteError(w, len(lineErrors), lineErrorMessages)
+		return
+	}
+
+	log.Printf("Successfully processed perfdata write request; points: %d \n", len(points))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func startGraphiteListeners(graphiteConfig config.Graphite) error {
+	parser, err := graphite.NewParser(graphiteConfig.Separator, graphiteConfig.Templates)
+	if err != nil {
+		return err
+	}
+
+	// like for the socket listeners, points are buffered so that writing the outputs does not block reading
+	buffer := listener.NewPointBuffer(cfg.WriteBatchSize, time.Duration(defaultSocketFlushInterval*int(time.Second)), func(points []general.Point) {
+		writeReceivedPoints("graphite", points)
+	})
+
+	handle := func(data []byte) {
+		points, lineErrors := parser.Parse(data, time.Now())
+		for _, lineError := range lineErrors {
+			log.Warnf("Rejected invalid graphite line: %v", lineError)
+		}
+		countReceived(len(data), len(points), len(lineErrors))
+		buffer.Add(points)
+	}
+
+	if graphiteConfig.TCPAddress != "" {
+		if _, err := listener.ListenTCP(graphiteConfig.TCPAddress, handle, &log); err != nil {
+			return fmt.Errorf("Failed to listen on tcp address %s: %w", graphiteConfig.TCPAddress, err)
+		}
+		log.Infof("Started graphite listener on tcp address %s", graphiteConfig.TCPAddress)
+	}
+	if graphiteConfig.UDPAddress != "" {
+		if _, err := listener.ListenUDP(graphiteConfig.UDPAddress, handle, &log); err != nil {
+			return fmt.Errorf("Failed to listen on udp address %s: %w", graphiteConfig.UDPAddress, err)
+		}
+		log.Infof("Started graphite listener on udp address %s", graphiteConfig.UDPAddress)
+	}
+
+	return nil
+}
+
+// statsd samples are aggregated and written to the outputs every flush interval
+func startStatsdListener(statsdConfig config.Statsd) error {
+	if statsdConfig.FlushInterval <= 0 {
+		return fmt.Errorf("Invalid statsd flush interval: %d", statsdConfig.FlushInterval)
+	}
+
+	aggregator, err := statsd.NewAggregator(statsdConfig.Percentiles, statsdConfig.DeleteGauges)
+	if err != nil {
+		return err
+	}
+
+	handle := func(data []byte) {
+		accepted, lineErrors := aggregator.Add(data)
+		for _, lineError := range lineErrors {
+			log.Warnf("Rejected invalid statsd line: %v", lineError)
+		}
+		countReceived(len(data), accepted, len(lineErrors))
+	}
+
+	if _, err := listener.ListenUDP(statsdConfig.UDPAddress, handle, &log); err != nil {
+		return fmt.Errorf("Failed to listen on udp address %s: %w", statsdConfig.UDPAddress, err)
+	}
+	log.Infof("Started statsd listener on udp address %s", statsdConfig.UDPAddress)
+
+	go func() {
+		for now := range time.Tick(time.Duration(statsdConfig.FlushInterval * int(time.Second))) {
+			writeReceivedPoints("statsd", aggregator.Flush(now))
+		}
+	}()
+
+	return nil
+}
+
+// default interval in which socket listeners write the buffered points, in seconds
+const defaultSocketFlushInterval = 1
+
+// socket listeners receive influx line protocol without the HTTP overhead, e.g. from devices that can only send UDP datagrams
+func startSocketListener(socketListenerConfig config.SocketListener) error {
+	precision, err := influx.ParsePrecision(socketListenerConfig.Precision)
+	if err != nil {
+		return err
+	}
+
+	batchSize := socketListenerConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = cfg.WriteBatchSize
+	}
+	flushInterval := socketListenerConfig.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultSocketFlushInterval
+	}
+	// points are buffered, writing the outputs for every datagram would block reading under high load
+	buffer := listener.NewPointBuffer(batchSize, time.Duration(flushInterval*int(time.Second)), func(points []general.Point) {
+		writeReceivedPoints("socket listener", points)
+	})
+
+	handle := func(data []byte) {
+		points, parseErr := influx.Parse(data, time.Now(), precision)
+		var lineErrors influx.ParseErrors
+		if parseErr != nil {
+			if !errors.As(parseErr, &lineErrors) {
+				log.Errorf("An error occurred while parsing line protocol received on %s: %v", socketListenerConfig.Address, parseErr)
+				return
+			}
+			log.Warnf("Rejected %d invalid lines received on %s: %v", len(lineErrors), socketListenerConfig.Address, parseErr)
+		}
+		countReceived(len(data), len(points), len(lineErrors))
+		buffer.Add(points)
+	}
+
+	parts := strings.SplitN(socketListenerConfig.Address, "://", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("Invalid socket listener address %s, expected tcp://, udp:// or unix://", socketListenerConfig.Address)
+	}
+	switch parts[0] {
+	case "tcp":
+		_, err = listener.ListenTCP(parts[1], handle, &log)
+	case "udp":
+		_, err = listener.ListenUDP(parts[1], handle, &log)
+	case "unix":
+		_, err = listener.ListenUnix(parts[1], handle, &log)
+	default:
+		return fmt.Errorf("Invalid socket listener address %s, expected tcp://, udp:// or unix://", socketListenerConfig.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to listen on %s: %w", socketListenerConfig.Address, err)
+	}
+	log.Infof("Started socket listener on %s", socketListenerConfig.Address)
+
+	return nil
+}
+
+// default interval and timeout for scrape targets, in seconds, and default maximum response size, in bytes
+const (
+	defaultScrapeInterval = 60
+	defaultScrapeTimeout  = 10
+	defaultScrapeMaxSize  = 10 * 1024 * 1024
+)
+
+// scrapers periodically pull metrics from targets that serve the prometheus text exposition format (e.g. exporters)
+func startScraper(scrapeTargetConfig config.ScrapeTarget) error {
+	interval := scrapeTargetConfig.Interval
+	if interval == 0 {
+		interval = defaultScrapeInterval
+	}
+	timeout := scrapeTargetConfig.Timeout
+	if timeout == 0 {
+		timeout = defaultScrapeTimeout
+		if timeout > interval {
+			timeout = interval
+		}
+	}
+	if interval < 0 || timeout < 0 || timeout > interval {
+		return fmt.Errorf("Invalid interval or timeout for scrape target %s, timeout must not exceed the interval", scrapeTargetConfig.URL)
+	}
+	maxSize := scrapeTargetConfig.MaxSize
+	if maxSize == 0 {
+		maxSize = defaultScrapeMaxSize
+	}
+	if maxSize < 0 {
+		return fmt.Errorf("Invalid max_size for scrape target %s", scrapeTargetConfig.URL)
+	}
+
+	scraper, err := prometheus.NewScraper(scrapeTargetConfig.URL, scrapeTargetConfig.Labels, time.Duration(timeout*int(time.Second)), maxSize)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for now := range time.Tick(time.Duration(interval * int(time.Second))) {
+			points, receivedBytes, err := scraper.Scrape(now)
+			if err != nil {
+				log.Warnf("Error scraping prometheus target: %v", err)
+				continue
+			}
+			countReceived(receivedBytes, len(points), 0)
+			writeReceivedPoints("prometheus scrape", points)
+		}
+	}()
+	log.Infof("Started scraping %s every %d seconds", scrapeTargetConfig.URL, interval)
+
+	return nil
+}
+
+// default poll interval for spool directories and tail files, in seconds
+const defaultPollInterval = 10
+
+// spool directories receive line protocol files, e.g. delivered by rsync from sites without network access to the receiver
+func startSpoolDirectory(spoolDirectoryConfig config.SpoolDirectory) error {
+	precision, err := influx.ParsePrecision(spoolDirectoryConfig.Precision)
+	if err != nil {
+		return err
+	}
+
+	// a file is either processed completely or not at all, so that it can be fixed and delivered again
+	process := func(data []byte) error {
+		points, parseErr := influx.Parse(data, time.Now(), precision)
+		var lineErrors influx.ParseErrors
+		if errors.As(parseErr, &lineErrors) {
+			countReceived(len(data), 0, len(lineErrors))
+		}
+		if parseErr != nil {
+			return parseErr
+		}
+		return writeFilePoints(data, points, 0)
+	}
+
+	directory, err := spool.NewDirectory(spoolDirectoryConfig.Directory, spoolDirectoryConfig.DoneDirectory, spoolDirectoryConfig.FailedDirectory, process, &log)
+	if err != nil {
+		return fmt.Errorf("Failed to open spool directory %s: %w", spoolDirectoryConfig.Directory, err)
+	}
+
+	startPolling(spoolDirectoryConfig.PollInterval, directory.Poll)
+	log.Infof("Started polling spool directory %s", spoolDirectoryConfig.Directory)
+
+	return nil
+}
+
+// tail files are growing line protocol files; as invalid lines can't be fixed and delivered again, they are skipped
+func startTailFile(tailFileConfig config.TailFile) error {
+	precision, err := influx.ParsePrecision(tailFileConfig.Precision)
+	if err != nil {
+		return err
+	}
+
+	process := func(data []byte) error {
+		points, parseErr := influx.Parse(data, time.Now(), precision)
+		var lineErrors influx.ParseErrors
+		if parseErr != nil {
+			if !errors.As(parseErr, &lineErrors) {
+				return parseErr
+			}
+			log.Warnf("Rejected %d invalid lines of %s: %v", len(lineErrors), tailFileConfig.Path, parseErr)
+		}
+		return writeFilePoints(data, points, len(lineErrors))
+	}
+
+	tail := spool.NewTail(tailFileConfig.Path, tailFileConfig.OffsetFile, process)
+
+	startPolling(tailFileConfig.PollInterval, tail.Poll)
+	log.Infof("Started tailing %s", tailFileConfig.Path)
+
+	return nil
+}
+
+// writeFilePoints writes the points read from a file; critical output errors are temporary, so that the file is processed again later
+func writeFilePoints(data []byte, points []general.Point, rejectedLines int) error {
+	criticalError, nonCriticalErrors := writeOutputs(points, "")
+	for _, nonCriticalError := range nonCriticalErrors {
+		log.Warnf("Non-critical error writing file points: %v", nonCriticalError)
+	}
+	if criticalError != nil {
+		return spool.TemporaryError{Err: criticalError}
+	}
+	countReceived(len(data), len(points), rejectedLines)
+	return nil
+}
+
+func startPolling(pollInterval int, poll func() error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	go func() {
+		for range time.Tick(time.Duration(pollInterval * int(time.Second))) {
+			if err := poll(); err != nil {
+				log.Errorf("%v", err)
+			}
+		}
+	}()
+}
+
+// countReceived updates the internal metrics for a message received by one of the listeners
+func countReceived(receivedBytes int, lines int, rejectedLines int) {
+	internalMetrics.internalMetricsLock.Lock()
+	internalMetrics.incomingMessagesCount += 1
+	internalMetrics.incomingBytesCount += int64(receivedBytes)
+	internalMetrics.incomingLinesCount += int64(lines)
+	internalMetrics.rejectedLinesCount += int64(rejectedLines)
+	internalMetrics.internalMetricsLock.Unlock()
+}
+
+// writeRequestPoints writes the points of a request to the outputs; a critical error is responded to the client, non-critical
+// errors are only logged. It returns whether the points were written and the request can be answered successfully.
+func writeRequestPoints(w http.ResponseWriter, points []general.Point) bool {
+	criticalError, nonCriticalErrors := writeOutputs(points, "")
+	if criticalError != nil {
+		log.Errorf(criticalError.Error())
+		http.Error(w, criticalError.Error(), http.StatusBadRequest)
+		return false
+	}
+	for _, nonCriticalError := range nonCriticalErrors {
+		log.Warnf(nonCriticalError.Error())
+	}
+	return true
+}
+
+// writeReceivedPoints writes points received by one of the listeners to the outputs;
+// as there is no client to report errors to, they are only logged
+func writeReceivedPoints(source string, points []general.Point) {
+	if len(points) == 0 {
+		return
+	}
+
+	criticalError, nonCriticalErrors := writeOutputs(points, "")
+	for _, nonCriticalError := range nonCriticalErrors {
+		log.Warnf("Non-critical error writing %s points: %v", source, nonCriticalError)
+	}
+	if criticalError != nil {
+		log.Errorf("Critical error writing %s points: %v", source, criticalError)
+	} else {
+		log.Debugf("Successfully processed %s points; lines: %d", source, len(points))
+	}
+}
+
+// processor chains of the outputs, by the index of the output in cfg.OutputsTimescale and cfg.OutputsInflux
+var timescaleProcessors, influxProcessors []*general.OutputProcessors
+
+// initProcessors creates the processor chains of all outputs and measurements once, so that configuration errors are reported at startup
+func initProcessors() error {
+	for i := range cfg.OutputsTimescale {
+		processors, err := general.NewOutputProcessors(&cfg.OutputsTimescale[i])
+		if err != nil {
+			return err
+		}
+		timescaleProcessors = append(timescaleProcessors, processors)
+	}
+	for i := range cfg.OutputsInflux {
+		processors, err := general.NewOutputProcessors(&cfg.OutputsInflux[i])
+		if err != nil {
+			return err
+		}
+		influxProcessors = append(influxProcessors, processors)
+	}
+	return nil
+}
+
+// writeOutputs writes the points to all outputs that accept the database/bucket db (empty for writes without database)
+func writeOutputs(points []general.Point, db string) (error, []error) {
 	var pointGroups = general.SplitPointsByMeasurement(points)
 	var nonCriticalErrors []error
 
 	// timescaledb outputs
-	for _, outputConfig := range cfg.OutputsTimescale {
-		preparedPoints, err := general.PreparePointGroups(pointGroups, &outputConfig, cfg.Enrichment.Sets, &log)
+	for i, outputConfig := range cfg.OutputsTimescale {
+		if !config.AcceptsDatabase(&outputConfig, db) {
+			continue
+		}
+
+		preparedPoints, err := timescaleProcessors[i].Prepare(pointGroups, cfg.Enrichment.Sets, &log)
 		if err != nil {
 			if outputConfig.WriteStrategy == "commit" {
 				return fmt.Errorf("An error occurred preparing timescaleDB output: %w", err), nonCriticalErrors
@@ -258,8 +1016,12 @@ func writeOutputs(points []general.Point) (error, []error) {
 	}
 
 	// influxdb outputs
-	for _, outputConfig := range cfg.OutputsInflux {
-		preparedPoints, err := general.PreparePointGroups(pointGroups, &outputConfig, cfg.Enrichment.Sets, &log)
+	for i, outputConfig := range cfg.OutputsInflux {
+		if !config.AcceptsDatabase(&outputConfig, db) {
+			continue
+		}
+
+		preparedPoints, err := influxProcessors[i].Prepare(pointGroups, cfg.Enrichment.Sets, &log)
 		if err != nil {
 			if outputConfig.WriteStrategy == "commit" {
 				return fmt.Errorf("An error occurred preparing influxDB output: %w", err), nonCriticalErrors
@@ -282,9 +1044,56 @@ func writeOutputs(points []general.Point) (error, []error) {
 	return nil, nonCriticalErrors
 }
 
-// GET /influx/v1/query
+// version of the influxDB API the receiver is compatible with, reported to clients that check it
+const influxCompatibleVersion = "1.8.10"
+
+// GET|HEAD /ping, /api/influx/v1/ping
+func influxPingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method is not supported.", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("X-Influxdb-Build", "OSS")
+	w.Header().Set("X-Influxdb-Version", influxCompatibleVersion)
+	w.Header().Set("X-Metrics-Receiver-Version", version)
+
+	if r.URL.Query().Get("verbose") == "true" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		jsonEncoder := json.NewEncoder(w)
+		jsonEncoder.Encode(map[string]string{"version": influxCompatibleVersion})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET|POST /query, /api/influx/v1/query
+// only answers the meta queries clients send on startup, see influx.ExecuteMetaQuery
 func influxQueryHandler(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, "Not supported", http.StatusForbidden)
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method is not supported.", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Influxdb-Build", "OSS")
+	w.Header().Set("X-Influxdb-Version", influxCompatibleVersion)
+	jsonEncoder := json.NewEncoder(w)
+
+	// FormValue covers the query parameter as well as url encoded POST bodies
+	query := r.FormValue("q")
+	if query == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		jsonEncoder.Encode(map[string]string{"error": "missing required parameter \"q\""})
+		return
+	}
+
+	log.Debugf("Answering influx query: %s", query)
+
+	w.WriteHeader(http.StatusOK)
+	jsonEncoder.Encode(influx.ExecuteMetaQuery(query, influxDatabases))
 }
 
 // GET /api/health/check
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+
+	"github.com/max-bytes/metrics-receiver/pkg/filter"
 )
 
 func ReadConfigFromFile(configFile string, cfg *Configuration) error {
@@ -31,42 +34,93 @@ type Configuration struct {
 	InternalMetricsCollectInterval int               `json:"internal_metrics_collect_interval"`
 	InternalMetricsFlushCycle      int               `json:"internal_metrics_flush_cycle"`
 	InternalMetricsMeasurement     string            `json:"internal_metrics_measurement"`
+	MaxBodySize                    int64             `json:"max_body_size"`
+	WriteBatchSize                 int               `json:"write_batch_size"`
 	Enrichment                     Enrichment        `json:"enrichment"`
+	Graphite                       Graphite          `json:"graphite"`
+	Statsd                         Statsd            `json:"statsd"`
+	SocketListeners                []SocketListener  `json:"socket_listeners"`
+	PrometheusScrapeTargets        []ScrapeTarget    `json:"prometheus_scrape_targets"`
+	SpoolDirectories               []SpoolDirectory  `json:"spool_directories"`
+	TailFiles                      []TailFile        `json:"tail_files"`
 	OutputsTimescale               []OutputTimescale `json:"outputs_timescaledb"`
 	OutputsInflux                  []OutputInflux    `json:"outputs_influxdb"`
 }
 
 type OutputConfig interface {
-	GetTagfilterInclude() map[string][]string
-	GetTagfilterBlock() map[string][]string
+	GetDatabases() []string
+	GetTagfilterInclude() TagFilter
+	GetTagfilterBlock() TagFilter
+	GetFilter() *filter.Expression
 	GetMeasurementConfig(name string) (MeasurementConfig, bool)
+	GetMeasurementNames() []string
+	GetProcessors() []ProcessorConfig
+}
+
+// AcceptsDatabase reports whether the output accepts writes to the database/bucket db; outputs without a databases list accept all writes,
+// writes without database (e.g. from listeners) are only accepted if the list contains ""
+func AcceptsDatabase(c OutputConfig, db string) bool {
+	databases := c.GetDatabases()
+	if len(databases) == 0 {
+		return true
+	}
+	for _, d := range databases {
+		if d == db {
+			return true
+		}
+	}
+	return false
 }
 
 type MeasurementConfig interface {
 	GetAddedTags() map[string]string
 	GetIgnore() bool
 	GetIgnoreFiltering() bool
+	GetTagfilterInclude() TagFilter
+	GetTagfilterBlock() TagFilter
+	GetTagfilterMode() TagfilterMode
+	GetFieldsInclude() ValueMatcher
+	GetFieldsExclude() ValueMatcher
 	GetEnrichment() string
+	GetFilter() *filter.Expression
+	GetProcessors() []ProcessorConfig
 }
 
 type OutputTimescale struct {
-	TagfilterInclude map[string][]string             `json:"tagfilter_include"`
-	TagfilterBlock   map[string][]string             `json:"tagfilter_block"`
+	Databases        []string                        `json:"databases"`
+	TagfilterInclude TagFilter                       `json:"tagfilter_include"`
+	TagfilterBlock   TagFilter                       `json:"tagfilter_block"`
+	Filter           *filter.Expression              `json:"filter"`
+	Processors       []ProcessorConfig               `json:"processors"`
 	WriteStrategy    string                          `json:"write_strategy"`
 	Measurements     map[string]MeasurementTimescale `json:"measurements"`
 	Connection       string                          `json:"connection"`
 }
 
-func (c *OutputTimescale) GetTagfilterInclude() map[string][]string { return c.TagfilterInclude }
-func (c *OutputTimescale) GetTagfilterBlock() map[string][]string   { return c.TagfilterBlock }
+func (c *OutputTimescale) GetDatabases() []string         { return c.Databases }
+func (c *OutputTimescale) GetTagfilterInclude() TagFilter { return c.TagfilterInclude }
+func (c *OutputTimescale) GetTagfilterBlock() TagFilter   { return c.TagfilterBlock }
+func (c *OutputTimescale) GetFilter() *filter.Expression  { return c.Filter }
 func (c *OutputTimescale) GetMeasurementConfig(name string) (MeasurementConfig, bool) {
 	m, ok := c.Measurements[name]
 	return m, ok
 }
+func (c *OutputTimescale) GetMeasurementNames() []string {
+	ret := make([]string, 0, len(c.Measurements))
+	for name := range c.Measurements {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+func (c *OutputTimescale) GetProcessors() []ProcessorConfig { return c.Processors }
 
 type OutputInflux struct {
-	TagfilterInclude map[string][]string          `json:"tagfilter_include"`
-	TagfilterBlock   map[string][]string          `json:"tagfilter_block"`
+	Databases        []string                     `json:"databases"`
+	TagfilterInclude TagFilter                    `json:"tagfilter_include"`
+	TagfilterBlock   TagFilter                    `json:"tagfilter_block"`
+	Filter           *filter.Expression           `json:"filter"`
+	Processors       []ProcessorConfig            `json:"processors"`
 	WriteStrategy    string                       `json:"write_strategy"`
 	Measurements     map[string]MeasurementInflux `json:"measurements"`
 	Connection       string                       `json:"connection"`
@@ -78,42 +132,138 @@ type OutputInflux struct {
 	Password         string                       `json:"password"`
 }
 
-func (c *OutputInflux) GetTagfilterInclude() map[string][]string { return c.TagfilterInclude }
-func (c *OutputInflux) GetTagfilterBlock() map[string][]string   { return c.TagfilterBlock }
+func (c *OutputInflux) GetDatabases() []string         { return c.Databases }
+func (c *OutputInflux) GetTagfilterInclude() TagFilter { return c.TagfilterInclude }
+func (c *OutputInflux) GetTagfilterBlock() TagFilter   { return c.TagfilterBlock }
+func (c *OutputInflux) GetFilter() *filter.Expression  { return c.Filter }
 func (c *OutputInflux) GetMeasurementConfig(name string) (MeasurementConfig, bool) {
 	m, ok := c.Measurements[name]
 	return m, ok
 }
+func (c *OutputInflux) GetMeasurementNames() []string {
+	ret := make([]string, 0, len(c.Measurements))
+	for name := range c.Measurements {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+func (c *OutputInflux) GetProcessors() []ProcessorConfig { return c.Processors }
 
 type MeasurementTimescale struct {
 	AddedTags       map[string]string
 	Ignore          bool
 	IgnoreFiltering bool
+	Filter          *filter.Expression
+
+	TagfilterInclude TagFilter
+	TagfilterBlock   TagFilter
+	TagfilterMode    TagfilterMode
+
+	FieldsInclude ValueMatcher
+	FieldsExclude ValueMatcher
 
 	FieldsAsColumns []string
 	TagsAsColumns   []string
 	TargetTable     string
 
 	Enrichment string
+
+	Processors []ProcessorConfig
 }
 
-func (c MeasurementTimescale) GetAddedTags() map[string]string { return c.AddedTags }
-func (c MeasurementTimescale) GetIgnore() bool                 { return c.Ignore }
-func (c MeasurementTimescale) GetIgnoreFiltering() bool        { return c.IgnoreFiltering }
-func (c MeasurementTimescale) GetEnrichment() string           { return c.Enrichment }
+func (c MeasurementTimescale) GetAddedTags() map[string]string  { return c.AddedTags }
+func (c MeasurementTimescale) GetIgnore() bool                  { return c.Ignore }
+func (c MeasurementTimescale) GetIgnoreFiltering() bool         { return c.IgnoreFiltering }
+func (c MeasurementTimescale) GetTagfilterInclude() TagFilter   { return c.TagfilterInclude }
+func (c MeasurementTimescale) GetTagfilterBlock() TagFilter     { return c.TagfilterBlock }
+func (c MeasurementTimescale) GetTagfilterMode() TagfilterMode  { return c.TagfilterMode }
+func (c MeasurementTimescale) GetFieldsInclude() ValueMatcher   { return c.FieldsInclude }
+func (c MeasurementTimescale) GetFieldsExclude() ValueMatcher   { return c.FieldsExclude }
+func (c MeasurementTimescale) GetEnrichment() string            { return c.Enrichment }
+func (c MeasurementTimescale) GetFilter() *filter.Expression    { return c.Filter }
+func (c MeasurementTimescale) GetProcessors() []ProcessorConfig { return c.Processors }
 
 type MeasurementInflux struct {
 	AddedTags       map[string]string
 	Ignore          bool
 	IgnoreFiltering bool
+	Filter          *filter.Expression
+
+	TagfilterInclude TagFilter
+	TagfilterBlock   TagFilter
+	TagfilterMode    TagfilterMode
+
+	FieldsInclude ValueMatcher
+	FieldsExclude ValueMatcher
 
 	Enrichment string
+
+	Processors []ProcessorConfig
+}
+
+func (c MeasurementInflux) GetAddedTags() map[string]string  { return c.AddedTags }
+func (c MeasurementInflux) GetIgnore() bool                  { return c.Ignore }
+func (c MeasurementInflux) GetIgnoreFiltering() bool         { return c.IgnoreFiltering }
+func (c MeasurementInflux) GetTagfilterInclude() TagFilter   { return c.TagfilterInclude }
+func (c MeasurementInflux) GetTagfilterBlock() TagFilter     { return c.TagfilterBlock }
+func (c MeasurementInflux) GetTagfilterMode() TagfilterMode  { return c.TagfilterMode }
+func (c MeasurementInflux) GetFieldsInclude() ValueMatcher   { return c.FieldsInclude }
+func (c MeasurementInflux) GetFieldsExclude() ValueMatcher   { return c.FieldsExclude }
+func (c MeasurementInflux) GetEnrichment() string            { return c.Enrichment }
+func (c MeasurementInflux) GetFilter() *filter.Expression    { return c.Filter }
+func (c MeasurementInflux) GetProcessors() []ProcessorConfig { return c.Processors }
+
+type Graphite struct {
+	TCPAddress string   `json:"tcp_address"`
+	UDPAddress string   `json:"udp_address"`
+	Separator  string   `json:"separator"`
+	Templates  []string `json:"templates"`
 }
 
-func (c MeasurementInflux) GetAddedTags() map[string]string { return c.AddedTags }
-func (c MeasurementInflux) GetIgnore() bool                 { return c.Ignore }
-func (c MeasurementInflux) GetIgnoreFiltering() bool        { return c.IgnoreFiltering }
-func (c MeasurementInflux) GetEnrichment() string           { return c.Enrichment }
+type Statsd struct {
+	UDPAddress    string    `json:"udp_address"`
+	FlushInterval int       `json:"flush_interval"`
+	Percentiles   []float64 `json:"percentiles"`
+	DeleteGauges  bool      `json:"delete_gauges"`
+}
+
+// SocketListener receives influx line protocol on a tcp, udp or unix domain socket; Address is of the form
+// "tcp://:8094", "udp://:8094" or "unix:///var/run/metrics-receiver.sock". The received points are written to the outputs
+// in batches of BatchSize points or every FlushInterval seconds
+type SocketListener struct {
+	Address       string `json:"address"`
+	Precision     string `json:"precision"`
+	BatchSize     int    `json:"batch_size"`
+	FlushInterval int    `json:"flush_interval"`
+}
+
+// ScrapeTarget is an url serving the prometheus text exposition format; Interval and Timeout are in seconds, MaxSize is the
+// maximum size of a response in bytes
+type ScrapeTarget struct {
+	URL      string            `json:"url"`
+	Labels   map[string]string `json:"labels"`
+	Interval int               `json:"interval"`
+	Timeout  int               `json:"timeout"`
+	MaxSize  int64             `json:"max_size"`
+}
+
+// SpoolDirectory is polled every PollInterval seconds for line protocol files, which are moved to DoneDirectory or FailedDirectory after processing
+type SpoolDirectory struct {
+	Directory       string `json:"directory"`
+	DoneDirectory   string `json:"done_directory"`
+	FailedDirectory string `json:"failed_directory"`
+	Precision       string `json:"precision"`
+	PollInterval    int    `json:"poll_interval"`
+}
+
+// TailFile is a growing line protocol file that is polled every PollInterval seconds; the processed offset is stored in OffsetFile
+type TailFile struct {
+	Path         string `json:"path"`
+	OffsetFile   string `json:"offset_file"`
+	Precision    string `json:"precision"`
+	PollInterval int    `json:"poll_interval"`
+}
 
 type Enrichment struct {
 	Sets            []EnrichmentSet `json:"sets"`
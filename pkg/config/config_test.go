@@ -0,0 +1,21 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptsDatabase(t *testing.T) {
+	all := &OutputTimescale{}
+	assert.True(t, AcceptsDatabase(all, "telegraf"))
+	assert.True(t, AcceptsDatabase(all, ""))
+
+	teams := &OutputInflux{Databases: []string{"team-a", "team-b"}}
+	assert.True(t, AcceptsDatabase(teams, "team-a"))
+	assert.False(t, AcceptsDatabase(teams, "team-c"))
+	assert.False(t, AcceptsDatabase(teams, ""))
+
+	withoutDatabase := &OutputInflux{Databases: []string{"team-a", ""}}
+	assert.True(t, AcceptsDatabase(withoutDatabase, ""))
+}
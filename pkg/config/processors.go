@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// ProcessorConfig is a step of the processor chain of an output or measurement; Type is one of the registered processor types
+// (built in: "ignore", "tagfilter", "filter", "fieldfilter", "rename", "convert", "enrichment" and "added_tags"), the other settings
+// depend on the type
+type ProcessorConfig struct {
+	Type string `json:"type"`
+
+	// rename: Tags and Fields map old to new keys, Measurement is the new measurement name
+	Tags        map[string]string `json:"tags"`
+	Fields      map[string]string `json:"fields"`
+	Measurement string            `json:"measurement"`
+	Rewrites    []ValueRewrite    `json:"rewrites"`
+
+	// convert: FieldsToTags are the fields that become tags, TagsToFields maps the tags that become fields to the field type
+	// ("float", "integer", "unsigned", "boolean" or "string")
+	FieldsToTags []string          `json:"fields_to_tags"`
+	TagsToFields map[string]string `json:"tags_to_fields"`
+}
+
+// ValueRewrite replaces the matches of Pattern in the value of the tag Tag or the string field Field with Replacement,
+// which can refer to capture groups ($1 or ${name})
+type ValueRewrite struct {
+	Tag         string  `json:"tag"`
+	Field       string  `json:"field"`
+	Pattern     *Regexp `json:"pattern"`
+	Replacement string  `json:"replacement"`
+}
+
+// Regexp is a regular expression that is compiled when the config is loaded
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func (r *Regexp) UnmarshalJSON(data []byte) error {
+	var expr string
+	if err := json.Unmarshal(data, &expr); err != nil {
+		return err
+	}
+	compiled, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid regular expression \"%s\": %w", expr, err)
+	}
+	r.Regexp = compiled
+	return nil
+}
+
+func (r Regexp) MarshalJSON() ([]byte, error) {
+	if r.Regexp == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(r.String())
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TagFilter maps tag keys to the matcher of their values, as used by tagfilter_include and tagfilter_block
+type TagFilter map[string]ValueMatcher
+
+// TagfilterMode defines how the tag filters of a measurement are applied together with the tag filters of the output
+type TagfilterMode string
+
+const (
+	// TagfilterModeCombine applies the tag filters of the output and of the measurement, this is the default
+	TagfilterModeCombine TagfilterMode = "combine"
+	// TagfilterModeOverride applies only the tag filters of the measurement, if it has any
+	TagfilterModeOverride TagfilterMode = "override"
+)
+
+func (m *TagfilterMode) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err != nil {
+		return err
+	}
+	switch TagfilterMode(mode) {
+	case "", TagfilterModeCombine, TagfilterModeOverride:
+		*m = TagfilterMode(mode)
+		return nil
+	default:
+		return fmt.Errorf("invalid tag filter mode \"%s\"", mode)
+	}
+}
+
+// ValueMatcher is a list of value patterns that is compiled when the config is loaded. A pattern is one of
+//
+//	"*"             any value
+//	"db-1"          the exact value
+//	"re:^db-.*$"    a regular expression
+//	"glob:db-*"     a glob, where * matches any sequence of characters and ? a single character
+//	"exact:re:x"    the exact value after the prefix, for values starting with one of the prefixes (or "exact:*")
+//
+// A pattern prefixed with "not:" is negated (e.g. "not:glob:db-test*"). "*" and values without prefix keep the meaning tag
+// filter values always had. Regular expressions are not written as "/^db-.*$/", as existing exact values (e.g. paths) may
+// start and end with "/". A value matches if it matches one of the patterns (or the list only has negated patterns) and
+// none of the negated patterns.
+type ValueMatcher struct {
+	Patterns []string
+	matches  []valuePattern
+	negated  []valuePattern
+}
+
+type valuePattern struct {
+	any    bool
+	exact  string
+	regexp *regexp.Regexp
+}
+
+// NewValueMatcher compiles the patterns
+func NewValueMatcher(patterns ...string) (ValueMatcher, error) {
+	ret := ValueMatcher{Patterns: patterns}
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, notPrefix)
+		p, err := compileValuePattern(strings.TrimPrefix(pattern, notPrefix))
+		if err != nil {
+			return ValueMatcher{}, fmt.Errorf("invalid pattern \"%s\": %w", pattern, err)
+		}
+		if negated {
+			ret.negated = append(ret.negated, p)
+		} else {
+			ret.matches = append(ret.matches, p)
+		}
+	}
+	return ret, nil
+}
+
+// prefixes of the value patterns
+const (
+	notPrefix    = "not:"
+	regexpPrefix = "re:"
+	globPrefix   = "glob:"
+	exactPrefix  = "exact:"
+)
+
+func compileValuePattern(pattern string) (valuePattern, error) {
+	switch {
+	case pattern == "*" || pattern == globPrefix+"*":
+		return valuePattern{any: true}, nil
+	case strings.HasPrefix(pattern, regexpPrefix):
+		r, err := regexp.Compile(strings.TrimPrefix(pattern, regexpPrefix))
+		return valuePattern{regexp: r}, err
+	case strings.HasPrefix(pattern, globPrefix):
+		return valuePattern{regexp: regexp.MustCompile(globToRegexp(strings.TrimPrefix(pattern, globPrefix)))}, nil
+	default:
+		return valuePattern{exact: strings.TrimPrefix(pattern, exactPrefix)}, nil
+	}
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+func (p valuePattern) match(value string) bool {
+	if p.any {
+		return true
+	}
+	if p.regexp != nil {
+		return p.regexp.MatchString(value)
+	}
+	return p.exact == value
+}
+
+// Matches reports whether value matches the patterns; an empty list matches no value
+func (m ValueMatcher) Matches(value string) bool {
+	if len(m.matches) == 0 && len(m.negated) == 0 {
+		return false
+	}
+	for _, p := range m.negated {
+		if p.match(value) {
+			return false
+		}
+	}
+	if len(m.matches) == 0 {
+		return true
+	}
+	for _, p := range m.matches {
+		if p.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ValueMatcher) UnmarshalJSON(data []byte) error {
+	var patterns []string
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return err
+	}
+	compiled, err := NewValueMatcher(patterns...)
+	if err != nil {
+		return err
+	}
+	*m = compiled
+	return nil
+}
+
+func (m ValueMatcher) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Patterns)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueMatcher(t *testing.T) {
+	var filter TagFilter
+	err := json.Unmarshal([]byte(`{
+		"any": ["*"],
+		"anyGlob": ["glob:*"],
+		"star": ["exact:*"],
+		"exact": ["host1", "host2"],
+		"plain": ["!db-*", "/db/"],
+		"regex": ["re:^db-[0-9]+$"],
+		"glob": ["glob:web-?.example.*"],
+		"negated": ["not:glob:test-*"],
+		"mixed": ["glob:db-*", "not:glob:db-test*", "not:db-1"],
+		"escaped": ["exact:re:x", "exact:not:y"],
+		"empty": []
+	}`), &filter)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		key      string
+		value    string
+		expected bool
+	}{
+		{"any", "", true},
+		{"any", "anything", true},
+		{"anyGlob", "anything", true},
+		{"star", "*", true},
+		{"star", "anything", false},
+		{"exact", "host2", true},
+		{"exact", "host3", false},
+		{"plain", "!db-*", true},
+		{"plain", "/db/", true},
+		{"plain", "db", false},
+		{"plain", "db-1", false},
+		{"regex", "db-12", true},
+		{"regex", "db-12a", false},
+		{"glob", "web-1.example.com", true},
+		{"glob", "web-12.example.com", false},
+		{"glob", "web-1xexample.com", false},
+		{"negated", "test-1", false},
+		{"negated", "prod-1", true},
+		{"mixed", "db-2", true},
+		{"mixed", "db-1", false},
+		{"mixed", "db-test1", false},
+		{"mixed", "web-1", false},
+		{"escaped", "re:x", true},
+		{"escaped", "not:y", true},
+		{"escaped", "x", false},
+		{"escaped", "y", false},
+		{"empty", "", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, filter[test.key].Matches(test.value), "%s: %s", test.key, test.value)
+	}
+
+	marshalled, err := json.Marshal(filter["mixed"])
+	assert.Nil(t, err)
+	assert.JSONEq(t, `["glob:db-*", "not:glob:db-test*", "not:db-1"]`, string(marshalled))
+}
+
+func TestValueMatcherInvalid(t *testing.T) {
+	var filter TagFilter
+	err := json.Unmarshal([]byte(`{"host": ["re:["]}`), &filter)
+	assert.EqualError(t, err, "invalid pattern \"re:[\": error parsing regexp: missing closing ]: `[`")
+}
+
+func TestTagfilterMode(t *testing.T) {
+	var m MeasurementInflux
+	assert.Nil(t, json.Unmarshal([]byte(`{"tagfilterMode": "override", "tagfilterInclude": {"host": ["glob:db-*"]}}`), &m))
+	assert.Equal(t, TagfilterModeOverride, m.TagfilterMode)
+	assert.True(t, m.TagfilterInclude["host"].Matches("db-1"))
+
+	err := json.Unmarshal([]byte(`{"tagfilterMode": "replace"}`), &m)
+	assert.EqualError(t, err, "invalid tag filter mode \"replace\"")
+}
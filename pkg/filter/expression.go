@@ -0,0 +1,488 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a compiled boolean filter expression on points, e.g. `host =~ "^sap" && customer != "stark" && value > 0`.
+//
+// Operands on the left side of a comparison are
+//
+//	measurement   the measurement name
+//	time          the timestamp, compared with RFC3339 strings or unix timestamps in seconds
+//	tags.<key>    a tag
+//	fields.<key>  a field
+//	<key>         a tag, or a field if there is no tag with this key
+//
+// and operands on the right side are "strings", numbers, true and false. The operators are =~ and !~ (regular expressions),
+// ==, !=, <, <=, >, >= (not for booleans), && (and), || (or), ! (not) and parentheses. A comparison with a missing tag or
+// field is false, except for != and !~, which are true.
+type Expression struct {
+	Source string
+	root   node
+}
+
+// Parse compiles an expression; an empty expression matches all points
+func Parse(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == tokenEOF {
+		return &Expression{Source: source}, nil
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", p.peek(), p.peek().pos)
+	}
+	return &Expression{Source: source, root: root}, nil
+}
+
+// Matches evaluates the expression for a point
+func (e *Expression) Matches(measurement string, tags map[string]string, fields map[string]interface{}, timestamp time.Time) bool {
+	if e.root == nil {
+		return true
+	}
+	return e.root.eval(&subject{measurement: measurement, tags: tags, fields: fields, timestamp: timestamp})
+}
+
+func (e *Expression) UnmarshalJSON(data []byte) error {
+	var source string
+	if err := json.Unmarshal(data, &source); err != nil {
+		return err
+	}
+	parsed, err := Parse(source)
+	if err != nil {
+		return fmt.Errorf("invalid filter expression \"%s\": %w", source, err)
+	}
+	*e = *parsed
+	return nil
+}
+
+func (e Expression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Source)
+}
+
+type subject struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]interface{}
+	timestamp   time.Time
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.value)
+	default:
+		return fmt.Sprintf("\"%s\"", t.value)
+	}
+}
+
+var operators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!"}
+
+func isIdentifierChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && (c == '.' || c == '-' || (c >= '0' && c <= '9')))
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	i := 0
+outer:
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLeftParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRightParen, ")", i})
+			i++
+		case c == '"':
+			start := i
+			var sb strings.Builder
+			for i++; i < len(source); i++ {
+				if source[i] == '\\' && i+1 < len(source) && (source[i+1] == '"' || source[i+1] == '\\') {
+					// other escapes are kept, e.g. for regular expressions like "^db\.example"
+					i++
+					sb.WriteByte(source[i])
+				} else if source[i] == '"' {
+					tokens = append(tokens, token{tokenString, sb.String(), start})
+					i++
+					continue outer
+				} else {
+					sb.WriteByte(source[i])
+				}
+			}
+			return nil, fmt.Errorf("unterminated string at position %d", start)
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			for i++; i < len(source) && (source[i] == '.' || source[i] == 'e' || source[i] == 'E' || (source[i] >= '0' && source[i] <= '9') ||
+				((source[i] == '-' || source[i] == '+') && (source[i-1] == 'e' || source[i-1] == 'E'))); i++ {
+			}
+			tokens = append(tokens, token{tokenNumber, source[start:i], start})
+		case isIdentifierChar(c, true):
+			start := i
+			for i++; i < len(source) && isIdentifierChar(source[i], false); i++ {
+			}
+			tokens = append(tokens, token{tokenIdentifier, source[start:i], start})
+		default:
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op, i})
+					i += len(op)
+					continue outer
+				}
+			}
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", c, i)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(source)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().value == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().value == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenOperator && t.value == "!":
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	case t.kind == tokenLeftParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, fmt.Errorf("expected \")\" at position %d, got %s", closing.pos, closing)
+		}
+		return inner, nil
+	case t.kind == tokenIdentifier:
+		return p.parseComparison(t)
+	default:
+		return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+	}
+}
+
+func (p *parser) parseComparison(identifier token) (node, error) {
+	op := p.next()
+	if op.kind != tokenOperator || op.value == "&&" || op.value == "||" || op.value == "!" {
+		return nil, fmt.Errorf("expected comparison operator after \"%s\" at position %d, got %s", identifier.value, op.pos, op)
+	}
+	literal := p.next()
+
+	c := comparisonNode{op: op.value}
+	switch {
+	case identifier.value == "measurement":
+		c.operand = operandMeasurement
+	case identifier.value == "time":
+		c.operand = operandTime
+	case strings.HasPrefix(identifier.value, "tags."):
+		c.operand, c.key = operandTag, strings.TrimPrefix(identifier.value, "tags.")
+	case strings.HasPrefix(identifier.value, "fields."):
+		c.operand, c.key = operandField, strings.TrimPrefix(identifier.value, "fields.")
+	default:
+		c.operand, c.key = operandTagOrField, identifier.value
+	}
+
+	switch {
+	case op.value == "=~" || op.value == "!~":
+		if literal.kind != tokenString {
+			return nil, fmt.Errorf("expected regular expression string at position %d, got %s", literal.pos, literal)
+		}
+		r, err := regexp.Compile(literal.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %w", literal.pos, err)
+		}
+		c.regexp = r
+	case literal.kind == tokenString:
+		c.value = literal.value
+	case literal.kind == tokenNumber:
+		f, err := strconv.ParseFloat(literal.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number \"%s\" at position %d", literal.value, literal.pos)
+		}
+		c.value = f
+	case literal.kind == tokenIdentifier && (literal.value == "true" || literal.value == "false"):
+		if op.value != "==" && op.value != "!=" {
+			return nil, fmt.Errorf("operator \"%s\" at position %d can't be used with booleans", op.value, op.pos)
+		}
+		c.value = literal.value == "true"
+	default:
+		return nil, fmt.Errorf("expected value at position %d, got %s", literal.pos, literal)
+	}
+
+	if c.operand == operandTime {
+		switch v := c.value.(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time at position %d: %w", literal.pos, err)
+			}
+			c.value = t
+		case float64:
+			sec, frac := math.Modf(v)
+			c.value = time.Unix(int64(sec), int64(frac*1e9))
+		default:
+			if c.regexp == nil {
+				return nil, fmt.Errorf("time can't be compared with %s at position %d", literal, literal.pos)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+type node interface {
+	eval(s *subject) bool
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(s *subject) bool { return n.left.eval(s) || n.right.eval(s) }
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(s *subject) bool { return n.left.eval(s) && n.right.eval(s) }
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(s *subject) bool { return !n.operand.eval(s) }
+
+type operand int
+
+const (
+	operandMeasurement operand = iota
+	operandTime
+	operandTag
+	operandField
+	operandTagOrField
+)
+
+type comparisonNode struct {
+	operand operand
+	key     string
+	op      string
+	value   interface{} // string, float64, bool or time.Time
+	regexp  *regexp.Regexp
+}
+
+func (n comparisonNode) lookup(s *subject) (interface{}, bool) {
+	switch n.operand {
+	case operandMeasurement:
+		return s.measurement, true
+	case operandTime:
+		return s.timestamp, true
+	case operandTag:
+		v, ok := s.tags[n.key]
+		return v, ok
+	case operandField:
+		v, ok := s.fields[n.key]
+		return v, ok
+	default:
+		if v, ok := s.tags[n.key]; ok {
+			return v, true
+		}
+		v, ok := s.fields[n.key]
+		return v, ok
+	}
+}
+
+func (n comparisonNode) eval(s *subject) bool {
+	actual, ok := n.lookup(s)
+	if !ok {
+		return n.op == "!=" || n.op == "!~"
+	}
+
+	if n.regexp != nil {
+		var str string
+		if t, isTime := actual.(time.Time); isTime {
+			str = t.Format(time.RFC3339Nano)
+		} else {
+			str = fmt.Sprint(actual)
+		}
+		return n.regexp.MatchString(str) == (n.op == "=~")
+	}
+
+	cmp, comparable := compare(actual, n.value)
+	if !comparable {
+		return n.op == "!="
+	}
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// compare returns -1, 0 or 1 if actual is less than, equal to or greater than expected, and false if they are not comparable
+func compare(actual interface{}, expected interface{}) (int, bool) {
+	switch e := expected.(type) {
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, e), true
+	case float64:
+		a, ok := toFloat(actual)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < e:
+			return -1, true
+		case a > e:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case bool:
+		a, ok := actual.(bool)
+		if !ok {
+			a, ok = parseBool(actual)
+		}
+		if !ok {
+			return 0, false
+		}
+		if a == e {
+			return 0, true
+		}
+		return 1, true
+	case time.Time:
+		a, ok := actual.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a.Before(e):
+			return -1, true
+		case a.After(e):
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// toFloat converts numeric fields and tags with numeric values
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func parseBool(v interface{}) (bool, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return false, false
+	}
+	b, err := strconv.ParseBool(s)
+	return b, err == nil
+}
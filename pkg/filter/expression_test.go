@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpression(t *testing.T) {
+	timestamp := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	tags := map[string]string{"host": "sap-db-1", "customer": "acme", "measurement": "exchange", "port": "8080"}
+	fields := map[string]interface{}{"value": 1.5, "count": int64(3), "up": true, "state": "ok", "host": "field-host"}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`host =~ "^sap" && customer != "stark" && value > 0`, true},
+		{`host =~ "^sap" && customer == "stark"`, false},
+		{`host =~ "^sap\.db"`, false},
+		{`host =~ "^sap-db\-1$"`, true},
+		{`host !~ "^sap"`, false},
+		{`customer == "stark" || count >= 3`, true},
+		{`!(customer == "acme")`, false},
+		{`! customer == "stark" && value < 2`, true},
+		{`value > 0 && (state == "critical" || count == 3)`, true},
+		{`value <= 1.5 && value >= 1.5 && value != 1`, true},
+		{`count < 3`, false},
+		{`up == true`, true},
+		{`up == false`, false},
+		{`port > 8000`, true},
+		{`missing == "x"`, false},
+		{`missing != "x"`, true},
+		{`missing > 0`, false},
+		{`missing !~ "x"`, true},
+		{`value == "1.5"`, false},
+		{`measurement == "metric"`, true},
+		{`measurement =~ "^met"`, true},
+		{`tags.measurement == "exchange"`, true},
+		{`host == "sap-db-1" && fields.host == "field-host"`, true},
+		{`tags.state == "ok"`, false},
+		{`fields.state == "ok"`, true},
+		{`time >= "2021-03-01T00:00:00Z" && time < "2021-03-02T00:00:00Z"`, true},
+		{`time > 1614599999.5`, true},
+		{`time == 1614600000`, true},
+		{`value > 1e0 && value < 2.5E+0 && count > -1`, true},
+		{`state == "o\"k"`, false},
+	}
+	for _, test := range tests {
+		expression, err := Parse(test.expression)
+		if assert.Nil(t, err, test.expression) {
+			assert.Equal(t, test.expected, expression.Matches("metric", tags, fields, timestamp), test.expression)
+		}
+	}
+}
+
+func TestExpressionInvalid(t *testing.T) {
+	tests := map[string]string{
+		`host ==`:                    "expected value at position 7, got end of expression",
+		`host "sap"`:                 "expected comparison operator after \"host\" at position 5, got \"sap\"",
+		`(host == "sap"`:             "expected \")\" at position 14, got end of expression",
+		`host == "sap")`:             "unexpected \")\" at position 13",
+		`host =~ 1`:                  "expected regular expression string at position 8, got \"1\"",
+		`host =~ "["`:                "invalid regular expression at position 8: error parsing regexp: missing closing ]: `[`",
+		`host == "sap`:               "unterminated string at position 8",
+		`host == 'sap'`:              "unexpected character ''' at position 8",
+		`time > "yesterday"`:         "invalid time at position 7: parsing time \"yesterday\" as \"2006-01-02T15:04:05.999999999Z07:00\": cannot parse \"yesterday\" as \"2006\"",
+		`host == "a" && && x == "b"`: "unexpected \"&&\" at position 15",
+		`up > false`:                 "operator \">\" at position 3 can't be used with booleans",
+		`up <= true`:                 "operator \"<=\" at position 3 can't be used with booleans",
+	}
+	for expression, expected := range tests {
+		_, err := Parse(expression)
+		assert.EqualError(t, err, expected, expression)
+	}
+}
+
+func TestExpressionJSON(t *testing.T) {
+	var c struct {
+		Filter *Expression `json:"filter"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(`{"filter": "host == \"a\""}`), &c))
+	assert.True(t, c.Filter.Matches("m", map[string]string{"host": "a"}, nil, time.Time{}))
+
+	marshalled, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"filter": "host == \"a\""}`, string(marshalled))
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"filter": " "}`), &c))
+	assert.True(t, c.Filter.Matches("m", nil, nil, time.Time{}))
+
+	err = json.Unmarshal([]byte(`{"filter": "host =="}`), &c)
+	assert.EqualError(t, err, "invalid filter expression \"host ==\": expected value at position 7, got end of expression")
+}
@@ -8,3 +8,22 @@ type Point struct {
 	Tags        map[string]string
 	Timestamp   time.Time
 }
+
+// CopyTags returns a copy of tags. The maps of a point are shared: the same points are prepared for every output and parsers
+// create several points from the same tags, so tags and fields are copied before they are modified.
+func CopyTags(tags map[string]string) map[string]string {
+	ret := make(map[string]string, len(tags))
+	for k, v := range tags {
+		ret[k] = v
+	}
+	return ret
+}
+
+// CopyFields returns a copy of fields, see CopyTags
+func CopyFields(fields map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		ret[k] = v
+	}
+	return ret
+}
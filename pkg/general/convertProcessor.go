@@ -0,0 +1,108 @@
+package general
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/max-bytes/metrics-receiver/pkg/config"
+)
+
+// convertProcessor moves fields to tags and tags to fields. Field values are formatted as tag values, tag values are parsed
+// as the configured field type; tags that can't be parsed (or are not finite floats) stay tags and fields that would
+// become empty tags stay fields. Points without remaining fields are dropped.
+type convertProcessor struct {
+	fieldsToTags []string
+	tagsToFields map[string]string
+}
+
+func newConvertProcessor(c config.ProcessorConfig) (Processor, error) {
+	for tag, fieldType := range c.TagsToFields {
+		switch fieldType {
+		case "float", "integer", "unsigned", "boolean", "string":
+		default:
+			return nil, fmt.Errorf("unknown field type \"%s\" for tag \"%s\"", fieldType, tag)
+		}
+	}
+	return &convertProcessor{fieldsToTags: c.FieldsToTags, tagsToFields: c.TagsToFields}, nil
+}
+
+func (p *convertProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	var ret []Point
+	for _, point := range points {
+		tags := CopyTags(point.Tags)
+		fields := CopyFields(point.Fields)
+
+		for _, key := range p.fieldsToTags {
+			v, ok := point.Fields[key]
+			if !ok {
+				continue
+			}
+			tagValue := formatTagValue(v)
+			if tagValue == "" {
+				ctx.Log.Debugf("Field %s of measurement %s is not converted to a tag: empty value", key, ctx.Measurement)
+				continue
+			}
+			delete(fields, key)
+			tags[key] = tagValue
+		}
+		for key, fieldType := range p.tagsToFields {
+			v, ok := point.Tags[key]
+			if !ok {
+				continue
+			}
+			fieldValue, err := parseFieldValue(v, fieldType)
+			if err != nil {
+				ctx.Log.Debugf("Tag %s of measurement %s is not converted to a field: %v", key, ctx.Measurement, err)
+				continue
+			}
+			delete(tags, key)
+			fields[key] = fieldValue
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+		point.Tags = tags
+		point.Fields = fields
+		ret = append(ret, point)
+	}
+	return ret, nil
+}
+
+func formatTagValue(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case uint64:
+		return strconv.FormatUint(t, 10)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// parseFieldValue parses a tag value as field of the type fieldType, which is validated in newConvertProcessor ("string" keeps the value)
+func parseFieldValue(v string, fieldType string) (interface{}, error) {
+	switch fieldType {
+	case "float":
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return nil, fmt.Errorf("%s is not a finite number", v)
+		}
+		return f, err
+	case "integer":
+		return strconv.ParseInt(v, 10, 64)
+	case "unsigned":
+		return strconv.ParseUint(v, 10, 64)
+	case "boolean":
+		return strconv.ParseBool(v)
+	default:
+		return v, nil
+	}
+}
@@ -4,85 +4,124 @@ import (
 	"fmt"
 
 	"github.com/max-bytes/metrics-receiver/pkg/config"
-	"github.com/max-bytes/metrics-receiver/pkg/enrichments"
 	"github.com/sirupsen/logrus"
 )
 
+// OutputProcessors are the processor chains of the measurements of an output; they are created once when the config is loaded
+// and reused for every write
+type OutputProcessors struct {
+	output       config.OutputConfig
+	measurements map[string]measurementProcessors
+}
+
+type measurementProcessors struct {
+	config     config.MeasurementConfig
+	processors []Processor
+}
+
+// NewOutputProcessors creates the processor chains of all measurements of the output; the processors of the output are
+// validated even if every measurement has its own
+func NewOutputProcessors(cfg config.OutputConfig) (*OutputProcessors, error) {
+	if _, err := NewProcessors(cfg.GetProcessors()); err != nil {
+		return nil, err
+	}
+
+	ret := &OutputProcessors{output: cfg, measurements: make(map[string]measurementProcessors)}
+	for _, measurement := range cfg.GetMeasurementNames() {
+		measurementConfig, _ := cfg.GetMeasurementConfig(measurement)
+		configs := processorConfigs(cfg, measurementConfig)
+		processors, err := NewProcessors(configs)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid processors of measurement \"%s\": %w", measurement, err)
+		}
+		// renamed points are written with the config of their new measurement, e.g. to its target table; as its processors are not
+		// applied to them, renaming into a measurement with its own filters is rejected instead of silently bypassing them
+		for _, c := range configs {
+			if c.Type != "rename" || c.Measurement == "" || c.Measurement == measurement {
+				continue
+			}
+			target, ok := cfg.GetMeasurementConfig(c.Measurement)
+			if !ok {
+				return nil, fmt.Errorf("Invalid processors of measurement \"%s\": measurement \"%s\" of rename is not configured", measurement, c.Measurement)
+			}
+			if hasOwnFilters(target) {
+				return nil, fmt.Errorf("Invalid processors of measurement \"%s\": measurement \"%s\" of rename has its own filters, which are not applied to renamed points", measurement, c.Measurement)
+			}
+		}
+		ret.measurements[measurement] = measurementProcessors{config: measurementConfig, processors: processors}
+	}
+	return ret, nil
+}
+
+// PreparePointGroups creates the processor chains of the output and prepares the point groups once, see OutputProcessors.Prepare
 func PreparePointGroups(i []PointGroup, cfg config.OutputConfig, enrichmentSets []config.EnrichmentSet, log *logrus.Logger) ([]PointGroup, error) {
+	processors, err := NewOutputProcessors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return processors.Prepare(i, enrichmentSets, log)
+}
+
+// Prepare applies the processor chain of each measurement to its point group; point groups without remaining points are dropped.
+// Points renamed to another measurement are regrouped under it, so that the output writes them with its measurement config;
+// the processors of the new measurement are not applied, but points renamed to an ignored measurement are dropped.
+func (p *OutputProcessors) Prepare(i []PointGroup, enrichmentSets []config.EnrichmentSet, log *logrus.Logger) ([]PointGroup, error) {
 	ret := make([]PointGroup, 0)
+	groups := make(map[string]int) // index of the point group of a measurement in ret
 	for _, input := range i {
 		var points = input.Points
 		var measurement = input.Measurement
 
 		// find measurement config
-		if _, ok := cfg.GetMeasurementConfig(measurement); !ok {
+		m, ok := p.measurements[measurement]
+		if !ok {
 			return nil, fmt.Errorf("Unknown measurement \"%s\" encountered", measurement)
 		}
-		measurementConfig, _ := cfg.GetMeasurementConfig(measurement)
 
-		// find enrichment set
-		enrichmentName := measurementConfig.GetEnrichment()
-		var enrichmentSet *config.EnrichmentSet
-		if enrichmentName != "" {
-			var enrichmentSetErr error
-			enrichmentSet, enrichmentSetErr = enrichments.FindEnrichmentSetByName(enrichmentName, enrichmentSets)
-			if enrichmentSetErr != nil {
-				return nil, fmt.Errorf("Unknown enrichment \"%s\" encountered", measurementConfig.GetEnrichment())
-			}
-			log.Debugf("Enriching points for measurement %s using enrichment set %s", measurement, enrichmentName)
+		ctx := &ProcessorContext{
+			Measurement:       measurement,
+			Output:            p.output,
+			MeasurementConfig: m.config,
+			EnrichmentSets:    enrichmentSets,
+			Log:               log,
 		}
-
-		// if this measurement should be ignored, continue with next point group
-		if measurementConfig.GetIgnore() {
-			continue
-		}
-
-		// filter points (if active)
-		if !measurementConfig.GetIgnoreFiltering() {
-			points = filterPoints(points, cfg)
-		}
-
-		// no points means an empty point group, which we can ignore
-		if len(points) == 0 {
-			continue
+		var err error
+		for _, processor := range m.processors {
+			points, err = processor.Process(points, ctx)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		// enrich points
-		var enrichedPoints []Point
+		// the points are added to the group of their (possibly renamed) measurement, measurements without remaining points get no group
 		for _, point := range points {
-
-			var tags = point.Tags
-
-			if enrichmentSet != nil {
-				var err error
-				tags, err = enrichments.EnrichTags(tags, enrichmentSet)
-				if err != nil {
-					return nil, err
-				}
+			target, ok := p.measurements[point.Measurement]
+			if !ok {
+				return nil, fmt.Errorf("Unknown measurement \"%s\" encountered", point.Measurement)
 			}
-
-			for k, v := range measurementConfig.GetAddedTags() {
-				tags[k] = v
+			if point.Measurement != measurement && target.config.GetIgnore() {
+				continue
 			}
-
-			enrichedPoints = append(enrichedPoints, Point{
-				Measurement: point.Measurement,
-				Fields:      point.Fields,
-				Tags:        tags,
-				Timestamp:   point.Timestamp})
+			group, ok := groups[point.Measurement]
+			if !ok {
+				group = len(ret)
+				groups[point.Measurement] = group
+				ret = append(ret, PointGroup{Measurement: point.Measurement})
+			}
+			ret[group].Points = append(ret[group].Points, point)
 		}
-
-		ret = append(ret, PointGroup{
-			Measurement: measurement,
-			Points:      enrichedPoints,
-		})
 	}
 	return ret, nil
 }
 
-func filterPoints(points []Point, c config.OutputConfig) []Point {
+// hasOwnFilters reports whether the measurement has tag filters, a filter expression or field filters of its own
+func hasOwnFilters(m config.MeasurementConfig) bool {
+	return len(m.GetTagfilterInclude()) > 0 || len(m.GetTagfilterBlock()) > 0 || m.GetFilter() != nil ||
+		len(m.GetFieldsInclude().Patterns) > 0 || len(m.GetFieldsExclude().Patterns) > 0
+}
+
+func filterPoints(points []Point, tagfilterInclude config.TagFilter, tagfilterBlock config.TagFilter) []Point {
 
-	var tagfilterInclude map[string][]string = c.GetTagfilterInclude()
 	var filteredPoints []Point
 	if len(tagfilterInclude) == 0 {
 		// no filtering
@@ -91,31 +130,22 @@ func filterPoints(points []Point, c config.OutputConfig) []Point {
 		for _, point := range points {
 		outInclude:
 			for tagKey, tagValue := range point.Tags {
-				if _, ok := tagfilterInclude[tagKey]; ok {
-					for _, v := range tagfilterInclude[tagKey] {
-						if v == "*" || tagValue == v {
-							filteredPoints = append(filteredPoints, point)
-							break outInclude
-						}
-					}
+				if matcher, ok := tagfilterInclude[tagKey]; ok && matcher.Matches(tagValue) {
+					filteredPoints = append(filteredPoints, point)
+					break outInclude
 				}
 			}
 		}
 	}
 
-	var tagfilterBlock map[string][]string = c.GetTagfilterBlock()
 	var keysToDelete []int
 	for pointKey, point := range filteredPoints {
 	outBlock:
 		for tagKey, tagValue := range point.Tags {
-			if _, ok := tagfilterBlock[tagKey]; ok {
-				for _, v := range tagfilterBlock[tagKey] {
-					if v == "*" || tagValue == v {
-						// index of the value to remove from points array
-						keysToDelete = append(keysToDelete, pointKey)
-						break outBlock
-					}
-				}
+			if matcher, ok := tagfilterBlock[tagKey]; ok && matcher.Matches(tagValue) {
+				// index of the value to remove from points array
+				keysToDelete = append(keysToDelete, pointKey)
+				break outBlock
 			}
 		}
 	}
@@ -0,0 +1,212 @@
+package general
+
+import (
+	"fmt"
+
+	"github.com/max-bytes/metrics-receiver/pkg/config"
+	"github.com/max-bytes/metrics-receiver/pkg/enrichments"
+	"github.com/max-bytes/metrics-receiver/pkg/filter"
+	"github.com/sirupsen/logrus"
+)
+
+// ProcessorContext describes the point group a processor is applied to
+type ProcessorContext struct {
+	Measurement       string
+	Output            config.OutputConfig
+	MeasurementConfig config.MeasurementConfig
+	EnrichmentSets    []config.EnrichmentSet
+	Log               *logrus.Logger
+}
+
+// Processor is a step of the chain that prepares the points of a measurement for an output; it returns the points that are
+// passed on to the next processor, returning no points drops the point group
+type Processor interface {
+	Process(points []Point, ctx *ProcessorContext) ([]Point, error)
+}
+
+// ProcessorFactory creates a processor from its configuration
+type ProcessorFactory func(c config.ProcessorConfig) (Processor, error)
+
+var processorFactories = map[string]ProcessorFactory{
+	"ignore":      func(c config.ProcessorConfig) (Processor, error) { return ignoreProcessor{}, nil },
+	"tagfilter":   func(c config.ProcessorConfig) (Processor, error) { return tagfilterProcessor{}, nil },
+	"filter":      func(c config.ProcessorConfig) (Processor, error) { return filterProcessor{}, nil },
+	"fieldfilter": func(c config.ProcessorConfig) (Processor, error) { return fieldfilterProcessor{}, nil },
+	"rename":      newRenameProcessor,
+	"convert":     newConvertProcessor,
+	"enrichment":  func(c config.ProcessorConfig) (Processor, error) { return enrichmentProcessor{}, nil },
+	"added_tags":  func(c config.ProcessorConfig) (Processor, error) { return addedTagsProcessor{}, nil },
+}
+
+// DefaultProcessors is the chain used for measurements and outputs without configured processors
+var DefaultProcessors = []config.ProcessorConfig{
+	{Type: "ignore"},
+	{Type: "tagfilter"},
+	{Type: "filter"},
+	{Type: "fieldfilter"},
+	{Type: "enrichment"},
+	{Type: "added_tags"},
+}
+
+// RegisterProcessor makes a processor type available to the processors configuration of outputs and measurements
+func RegisterProcessor(processorType string, factory ProcessorFactory) {
+	processorFactories[processorType] = factory
+}
+
+// NewProcessors creates the processors of a chain in the configured order
+func NewProcessors(configs []config.ProcessorConfig) ([]Processor, error) {
+	ret := make([]Processor, 0, len(configs))
+	for _, c := range configs {
+		factory, ok := processorFactories[c.Type]
+		if !ok {
+			return nil, fmt.Errorf("Unknown processor type \"%s\"", c.Type)
+		}
+		processor, err := factory(c)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid processor \"%s\": %w", c.Type, err)
+		}
+		ret = append(ret, processor)
+	}
+	return ret, nil
+}
+
+// processorConfigs returns the chain of a measurement: its own processors, else the processors of the output, else the default chain
+func processorConfigs(c config.OutputConfig, measurementConfig config.MeasurementConfig) []config.ProcessorConfig {
+	if processors := measurementConfig.GetProcessors(); len(processors) > 0 {
+		return processors
+	}
+	if processors := c.GetProcessors(); len(processors) > 0 {
+		return processors
+	}
+	return DefaultProcessors
+}
+
+// ignoreProcessor drops all points of measurements configured with Ignore
+type ignoreProcessor struct{}
+
+func (ignoreProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	if ctx.MeasurementConfig.GetIgnore() {
+		return nil, nil
+	}
+	return points, nil
+}
+
+// tagfilterProcessor applies the tag filters of the output and of the measurement; the tag filters of the output are skipped
+// for measurements configured with IgnoreFiltering and for measurements with own tag filters in TagfilterModeOverride
+type tagfilterProcessor struct{}
+
+func (tagfilterProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	measurementInclude := ctx.MeasurementConfig.GetTagfilterInclude()
+	measurementBlock := ctx.MeasurementConfig.GetTagfilterBlock()
+	override := ctx.MeasurementConfig.GetTagfilterMode() == config.TagfilterModeOverride && (len(measurementInclude) > 0 || len(measurementBlock) > 0)
+
+	if !ctx.MeasurementConfig.GetIgnoreFiltering() && !override {
+		points = filterPoints(points, ctx.Output.GetTagfilterInclude(), ctx.Output.GetTagfilterBlock())
+	}
+	return filterPoints(points, measurementInclude, measurementBlock), nil
+}
+
+// filterProcessor keeps the points matching the filter expressions of the output and the measurement; the filter of the output is
+// skipped for measurements configured with IgnoreFiltering
+type filterProcessor struct{}
+
+func (filterProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	var filters []*filter.Expression
+	if f := ctx.Output.GetFilter(); f != nil && !ctx.MeasurementConfig.GetIgnoreFiltering() {
+		filters = append(filters, f)
+	}
+	if f := ctx.MeasurementConfig.GetFilter(); f != nil {
+		filters = append(filters, f)
+	}
+	if len(filters) == 0 {
+		return points, nil
+	}
+
+	var ret []Point
+outer:
+	for _, point := range points {
+		for _, f := range filters {
+			if !f.Matches(point.Measurement, point.Tags, point.Fields, point.Timestamp) {
+				continue outer
+			}
+		}
+		ret = append(ret, point)
+	}
+	return ret, nil
+}
+
+// fieldfilterProcessor keeps the fields matching FieldsInclude (all fields if it is empty) and drops the fields matching FieldsExclude;
+// points without remaining fields are dropped
+type fieldfilterProcessor struct{}
+
+func (fieldfilterProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	include := ctx.MeasurementConfig.GetFieldsInclude()
+	exclude := ctx.MeasurementConfig.GetFieldsExclude()
+	if len(include.Patterns) == 0 && len(exclude.Patterns) == 0 {
+		return points, nil
+	}
+
+	var ret []Point
+	for _, point := range points {
+		fields := make(map[string]interface{}, len(point.Fields))
+		for k, v := range point.Fields {
+			if (len(include.Patterns) == 0 || include.Matches(k)) && !exclude.Matches(k) {
+				fields[k] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		point.Fields = fields
+		ret = append(ret, point)
+	}
+	return ret, nil
+}
+
+// enrichmentProcessor adds the tags of the enrichment set configured for the measurement
+type enrichmentProcessor struct{}
+
+func (enrichmentProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	enrichmentName := ctx.MeasurementConfig.GetEnrichment()
+	if enrichmentName == "" {
+		return points, nil
+	}
+
+	enrichmentSet, err := enrichments.FindEnrichmentSetByName(enrichmentName, ctx.EnrichmentSets)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown enrichment \"%s\" encountered", enrichmentName)
+	}
+	ctx.Log.Debugf("Enriching points for measurement %s using enrichment set %s", ctx.Measurement, enrichmentName)
+
+	ret := make([]Point, 0, len(points))
+	for _, point := range points {
+		tags, err := enrichments.EnrichTags(point.Tags, enrichmentSet)
+		if err != nil {
+			return nil, err
+		}
+		point.Tags = tags
+		ret = append(ret, point)
+	}
+	return ret, nil
+}
+
+// addedTagsProcessor adds the AddedTags of the measurement, overwriting existing tags
+type addedTagsProcessor struct{}
+
+func (addedTagsProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	addedTags := ctx.MeasurementConfig.GetAddedTags()
+	if len(addedTags) == 0 {
+		return points, nil
+	}
+
+	ret := make([]Point, 0, len(points))
+	for _, point := range points {
+		tags := CopyTags(point.Tags)
+		for k, v := range addedTags {
+			tags[k] = v
+		}
+		point.Tags = tags
+		ret = append(ret, point)
+	}
+	return ret, nil
+}
@@ -0,0 +1,395 @@
+package general
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/config"
+	"github.com/max-bytes/metrics-receiver/pkg/filter"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustValueMatcher(patterns ...string) config.ValueMatcher {
+	m, err := config.NewValueMatcher(patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func mustFilter(source string) *filter.Expression {
+	f, err := filter.Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func mustProcessors(source string) []config.ProcessorConfig {
+	var processors []config.ProcessorConfig
+	if err := json.Unmarshal([]byte(source), &processors); err != nil {
+		panic(err)
+	}
+	return processors
+}
+
+// copyPointGroups copies the tags and fields of the points, to check that preparing does not modify its input
+func copyPointGroups(pointGroups []PointGroup) []PointGroup {
+	ret := make([]PointGroup, 0, len(pointGroups))
+	for _, pointGroup := range pointGroups {
+		points := make([]Point, 0, len(pointGroup.Points))
+		for _, point := range pointGroup.Points {
+			point.Tags = CopyTags(point.Tags)
+			point.Fields = CopyFields(point.Fields)
+			points = append(points, point)
+		}
+		ret = append(ret, PointGroup{Measurement: pointGroup.Measurement, Points: points})
+	}
+	return ret
+}
+
+type upperCaseHostProcessor struct{}
+
+func (upperCaseHostProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	ret := make([]Point, 0, len(points))
+	for _, point := range points {
+		point.Tags = CopyTags(point.Tags)
+		point.Tags["host"] = strings.ToUpper(point.Tags["host"])
+		ret = append(ret, point)
+	}
+	return ret, nil
+}
+
+func TestPreparePointGroups(t *testing.T) {
+	RegisterProcessor("upper_case_host", func(c config.ProcessorConfig) (Processor, error) { return upperCaseHostProcessor{}, nil })
+	RegisterProcessor("invalid", func(c config.ProcessorConfig) (Processor, error) { return nil, errors.New("invalid") })
+
+	t1 := time.Now()
+	metric := []PointGroup{{Measurement: "metric", Points: []Point{
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "host1"}, Timestamp: t1},
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 2.0}, Tags: map[string]string{"host": "host2"}, Timestamp: t1},
+	}}}
+	filtered := []PointGroup{{Measurement: "metric", Points: []Point{
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "sap-1", "customer": "acme"}, Timestamp: t1},
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 0.0}, Tags: map[string]string{"host": "sap-2", "customer": "acme"}, Timestamp: t1},
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "sap-3", "customer": "stark"}, Timestamp: t1},
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "web-1", "customer": "acme"}, Timestamp: t1},
+	}}}
+	sapBmc := []PointGroup{{Measurement: "sap_bmc", Points: []Point{
+		{Measurement: "sap_bmc", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "sap-1", "customer": "acme"}, Timestamp: t1},
+		{Measurement: "sap_bmc", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "sap-2", "customer": "stark"}, Timestamp: t1},
+		{Measurement: "sap_bmc", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "web-1", "customer": "acme"}, Timestamp: t1},
+	}}}
+	sapBmcInclude := config.TagFilter{"customer": mustValueMatcher("acme", "stark")}
+	sapBmcBlock := config.TagFilter{"host": mustValueMatcher("glob:web-*")}
+	cpu := []PointGroup{{Measurement: "cpu", Points: []Point{
+		{Measurement: "cpu", Fields: map[string]interface{}{"usage_user": 1.0, "usage_system": 2.0, "usage_guest": 0.0, "time_user": 10.0}, Tags: map[string]string{"host": "host1"}, Timestamp: t1},
+		{Measurement: "cpu", Fields: map[string]interface{}{"time_user": 10.0}, Tags: map[string]string{"host": "host2"}, Timestamp: t1},
+	}}}
+	renamed := []PointGroup{{Measurement: "cpu", Points: []Point{
+		{Measurement: "cpu", Fields: map[string]interface{}{"val": 1.0, "state": "state_ok"}, Tags: map[string]string{"hostname": "host1.example.com"}, Timestamp: t1},
+		{Measurement: "cpu", Fields: map[string]interface{}{"value": 2.0}, Tags: map[string]string{"HOST": "HOST2", "service": "cpu"}, Timestamp: t1},
+	}}}
+	converted := []PointGroup{{Measurement: "metric", Points: []Point{
+		{Measurement: "metric", Fields: map[string]interface{}{"ciid": "CI-1", "value": 1.5, "count": int64(3)}, Tags: map[string]string{"host": "host1", "warn": "80", "up": "true"}, Timestamp: t1},
+		{Measurement: "metric", Fields: map[string]interface{}{"ciid": "CI-2"}, Tags: map[string]string{"host": "host2", "warn": "n/a"}, Timestamp: t1},
+		{Measurement: "metric", Fields: map[string]interface{}{"ciid": "CI-3"}, Tags: map[string]string{"host": "host3"}, Timestamp: t1},
+		{Measurement: "metric", Fields: map[string]interface{}{"ciid": ""}, Tags: map[string]string{"host": "host4", "warn": "NaN", "crit": "-Inf"}, Timestamp: t1},
+	}}}
+
+	tests := []struct {
+		name        string
+		cfg         config.OutputConfig
+		input       []PointGroup
+		expected    []PointGroup
+		expectedErr string
+	}{
+		{
+			name: "default chain",
+			cfg: &config.OutputInflux{
+				TagfilterInclude: config.TagFilter{"host": mustValueMatcher("host1")},
+				Measurements:     map[string]config.MeasurementInflux{"metric": {AddedTags: map[string]string{"added_tag": "added_tag_value"}}},
+			},
+			input: metric,
+			expected: []PointGroup{{Measurement: "metric", Points: []Point{
+				{Measurement: "metric", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "host1", "added_tag": "added_tag_value"}, Timestamp: t1},
+			}}},
+		},
+		{
+			name: "the processors of the output replace the default chain",
+			cfg: &config.OutputInflux{
+				TagfilterInclude: config.TagFilter{"host": mustValueMatcher("HOST2")},
+				Processors:       []config.ProcessorConfig{{Type: "added_tags"}, {Type: "upper_case_host"}, {Type: "tagfilter"}},
+				Measurements:     map[string]config.MeasurementInflux{"metric": {AddedTags: map[string]string{"added_tag": "added_tag_value"}}},
+			},
+			input: metric,
+			expected: []PointGroup{{Measurement: "metric", Points: []Point{
+				{Measurement: "metric", Fields: map[string]interface{}{"value": 2.0}, Tags: map[string]string{"host": "HOST2", "added_tag": "added_tag_value"}, Timestamp: t1},
+			}}},
+		},
+		{
+			name: "the processors of the measurement replace the processors of the output",
+			cfg: &config.OutputInflux{
+				Processors:   []config.ProcessorConfig{{Type: "added_tags"}},
+				Measurements: map[string]config.MeasurementInflux{"metric": {Ignore: true, Processors: []config.ProcessorConfig{{Type: "ignore"}, {Type: "added_tags"}}}},
+			},
+			input:    metric,
+			expected: []PointGroup{},
+		},
+		{
+			name:        "unknown processor",
+			cfg:         &config.OutputInflux{Measurements: map[string]config.MeasurementInflux{"metric": {Processors: []config.ProcessorConfig{{Type: "unknown"}}}}},
+			input:       metric,
+			expectedErr: "Invalid processors of measurement \"metric\": Unknown processor type \"unknown\"",
+		},
+		{
+			name:        "invalid processor",
+			cfg:         &config.OutputInflux{Measurements: map[string]config.MeasurementInflux{"metric": {Processors: []config.ProcessorConfig{{Type: "invalid"}}}}},
+			input:       metric,
+			expectedErr: "Invalid processors of measurement \"metric\": Invalid processor \"invalid\": invalid",
+		},
+		{
+			name:        "unknown measurement",
+			cfg:         &config.OutputInflux{},
+			input:       metric,
+			expectedErr: "Unknown measurement \"metric\" encountered",
+		},
+		{
+			name: "the filters of the output and the measurement are applied",
+			cfg: &config.OutputTimescale{
+				Filter:       mustFilter(`host =~ "^sap" && customer != "stark"`),
+				Measurements: map[string]config.MeasurementTimescale{"metric": {Filter: mustFilter(`value > 0`)}},
+			},
+			input:    filtered,
+			expected: []PointGroup{{Measurement: "metric", Points: []Point{filtered[0].Points[0]}}},
+		},
+		{
+			name: "IgnoreFiltering skips the filter of the output",
+			cfg: &config.OutputTimescale{
+				Filter:       mustFilter(`host =~ "^sap" && customer != "stark"`),
+				Measurements: map[string]config.MeasurementTimescale{"metric": {Filter: mustFilter(`value > 0`), IgnoreFiltering: true}},
+			},
+			input:    filtered,
+			expected: []PointGroup{{Measurement: "metric", Points: []Point{filtered[0].Points[0], filtered[0].Points[2], filtered[0].Points[3]}}},
+		},
+		{
+			name: "the tag filters of the output and the measurement are combined by default",
+			cfg: &config.OutputInflux{
+				TagfilterInclude: sapBmcInclude,
+				TagfilterBlock:   sapBmcBlock,
+				Measurements:     map[string]config.MeasurementInflux{"sap_bmc": {TagfilterBlock: config.TagFilter{"customer": mustValueMatcher("stark")}}},
+			},
+			input:    sapBmc,
+			expected: []PointGroup{{Measurement: "sap_bmc", Points: []Point{sapBmc[0].Points[0]}}},
+		},
+		{
+			name: "in override mode only the tag filters of the measurement are applied",
+			cfg: &config.OutputInflux{
+				TagfilterInclude: sapBmcInclude,
+				TagfilterBlock:   sapBmcBlock,
+				Measurements: map[string]config.MeasurementInflux{"sap_bmc": {
+					TagfilterBlock: config.TagFilter{"customer": mustValueMatcher("stark")},
+					TagfilterMode:  config.TagfilterModeOverride,
+				}},
+			},
+			input:    sapBmc,
+			expected: []PointGroup{{Measurement: "sap_bmc", Points: []Point{sapBmc[0].Points[0], sapBmc[0].Points[2]}}},
+		},
+		{
+			name: "measurements without own tag filters use the tag filters of the output, even in override mode",
+			cfg: &config.OutputInflux{
+				TagfilterInclude: sapBmcInclude,
+				TagfilterBlock:   sapBmcBlock,
+				Measurements:     map[string]config.MeasurementInflux{"sap_bmc": {TagfilterMode: config.TagfilterModeOverride}},
+			},
+			input:    sapBmc,
+			expected: []PointGroup{{Measurement: "sap_bmc", Points: []Point{sapBmc[0].Points[0], sapBmc[0].Points[1]}}},
+		},
+		{
+			name: "IgnoreFiltering only skips the tag filters of the output",
+			cfg: &config.OutputInflux{
+				TagfilterInclude: sapBmcInclude,
+				TagfilterBlock:   sapBmcBlock,
+				Measurements: map[string]config.MeasurementInflux{"sap_bmc": {
+					IgnoreFiltering:  true,
+					TagfilterInclude: config.TagFilter{"host": mustValueMatcher("re:-1$")},
+				}},
+			},
+			input:    sapBmc,
+			expected: []PointGroup{{Measurement: "sap_bmc", Points: []Point{sapBmc[0].Points[0], sapBmc[0].Points[2]}}},
+		},
+		{
+			name: "fields are included and excluded, points without remaining fields are dropped",
+			cfg: &config.OutputTimescale{Measurements: map[string]config.MeasurementTimescale{
+				"cpu": {FieldsInclude: mustValueMatcher("glob:usage_*"), FieldsExclude: mustValueMatcher("glob:*_guest")},
+			}},
+			input: cpu,
+			expected: []PointGroup{{Measurement: "cpu", Points: []Point{
+				{Measurement: "cpu", Fields: map[string]interface{}{"usage_user": 1.0, "usage_system": 2.0}, Tags: map[string]string{"host": "host1"}, Timestamp: t1},
+			}}},
+		},
+		{
+			name: "fields are only excluded",
+			cfg: &config.OutputTimescale{Measurements: map[string]config.MeasurementTimescale{
+				"cpu": {FieldsExclude: mustValueMatcher("glob:time_*")},
+			}},
+			input: cpu,
+			expected: []PointGroup{{Measurement: "cpu", Points: []Point{
+				{Measurement: "cpu", Fields: map[string]interface{}{"usage_user": 1.0, "usage_system": 2.0, "usage_guest": 0.0}, Tags: map[string]string{"host": "host1"}, Timestamp: t1},
+			}}},
+		},
+		{
+			name: "rename",
+			cfg: &config.OutputInflux{
+				Processors: mustProcessors(`[{
+					"type": "rename",
+					"tags": {"hostname": "host", "HOST": "host"},
+					"fields": {"val": "value"},
+					"measurement": "cpu_usage",
+					"rewrites": [
+						{"tag": "host", "pattern": "^([^.]+)\\..*$", "replacement": "$1"},
+						{"field": "state", "pattern": "^state_(?P<state>.*)$", "replacement": "${state}"}
+					]
+				}]`),
+				Measurements: map[string]config.MeasurementInflux{"cpu": {}, "cpu_usage": {}},
+			},
+			input: renamed,
+			expected: []PointGroup{{Measurement: "cpu_usage", Points: []Point{
+				{Measurement: "cpu_usage", Fields: map[string]interface{}{"value": 1.0, "state": "ok"}, Tags: map[string]string{"host": "host1"}, Timestamp: t1},
+				{Measurement: "cpu_usage", Fields: map[string]interface{}{"value": 2.0}, Tags: map[string]string{"host": "HOST2", "service": "cpu"}, Timestamp: t1},
+			}}},
+		},
+		{
+			name: "renamed points are merged into the group of their new measurement",
+			cfg: &config.OutputInflux{Measurements: map[string]config.MeasurementInflux{
+				"cpu":       {Processors: []config.ProcessorConfig{{Type: "rename", Measurement: "cpu_usage"}}},
+				"cpu_usage": {AddedTags: map[string]string{"added_tag": "added_tag_value"}},
+			}},
+			input: append([]PointGroup{{Measurement: "cpu_usage", Points: []Point{
+				{Measurement: "cpu_usage", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "host1"}, Timestamp: t1},
+			}}}, renamed...),
+			// the processors of the new measurement are not applied to the renamed points
+			expected: []PointGroup{{Measurement: "cpu_usage", Points: []Point{
+				{Measurement: "cpu_usage", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "host1", "added_tag": "added_tag_value"}, Timestamp: t1},
+				{Measurement: "cpu_usage", Fields: renamed[0].Points[0].Fields, Tags: renamed[0].Points[0].Tags, Timestamp: t1},
+				{Measurement: "cpu_usage", Fields: renamed[0].Points[1].Fields, Tags: renamed[0].Points[1].Tags, Timestamp: t1},
+			}}},
+		},
+		{
+			name: "the new measurement of a rename has to be configured",
+			cfg: &config.OutputInflux{Measurements: map[string]config.MeasurementInflux{
+				"cpu": {Processors: []config.ProcessorConfig{{Type: "rename", Measurement: "cpu_usage"}}},
+			}},
+			input:       renamed,
+			expectedErr: "Invalid processors of measurement \"cpu\": measurement \"cpu_usage\" of rename is not configured",
+		},
+		{
+			name: "points renamed to an ignored measurement are dropped",
+			cfg: &config.OutputInflux{Measurements: map[string]config.MeasurementInflux{
+				"cpu":       {Processors: []config.ProcessorConfig{{Type: "rename", Measurement: "cpu_usage"}}},
+				"cpu_usage": {Ignore: true},
+			}},
+			input:    renamed,
+			expected: []PointGroup{},
+		},
+		{
+			name: "the new measurement of a rename can't have its own filters",
+			cfg: &config.OutputInflux{Measurements: map[string]config.MeasurementInflux{
+				"cpu":       {Processors: []config.ProcessorConfig{{Type: "rename", Measurement: "cpu_usage"}}},
+				"cpu_usage": {TagfilterBlock: config.TagFilter{"host": mustValueMatcher("host1")}},
+			}},
+			input:       renamed,
+			expectedErr: "Invalid processors of measurement \"cpu\": measurement \"cpu_usage\" of rename has its own filters, which are not applied to renamed points",
+		},
+		{
+			name: "convert, tags that can't be parsed and empty fields are not converted",
+			cfg: &config.OutputTimescale{Measurements: map[string]config.MeasurementTimescale{
+				"metric": {Processors: mustProcessors(`[
+					{"type": "convert", "fields_to_tags": ["ciid", "count"], "tags_to_fields": {"warn": "float", "crit": "float", "up": "boolean", "host": "string"}}
+				]`)},
+			}},
+			input: converted,
+			expected: []PointGroup{{Measurement: "metric", Points: []Point{
+				{Measurement: "metric", Fields: map[string]interface{}{"value": 1.5, "warn": 80.0, "up": true, "host": "host1"}, Tags: map[string]string{"ciid": "CI-1", "count": "3"}, Timestamp: t1},
+				{Measurement: "metric", Fields: map[string]interface{}{"host": "host2"}, Tags: map[string]string{"ciid": "CI-2", "warn": "n/a"}, Timestamp: t1},
+				{Measurement: "metric", Fields: map[string]interface{}{"host": "host3"}, Tags: map[string]string{"ciid": "CI-3"}, Timestamp: t1},
+				// non-finite floats stay tags, empty fields stay fields
+				{Measurement: "metric", Fields: map[string]interface{}{"host": "host4", "ciid": ""}, Tags: map[string]string{"warn": "NaN", "crit": "-Inf"}, Timestamp: t1},
+			}}},
+		},
+		{
+			name: "convert, points without remaining fields are dropped",
+			cfg: &config.OutputTimescale{Measurements: map[string]config.MeasurementTimescale{
+				"metric": {Processors: []config.ProcessorConfig{{Type: "convert", FieldsToTags: []string{"ciid"}}}},
+			}},
+			input: converted,
+			expected: []PointGroup{{Measurement: "metric", Points: []Point{
+				{Measurement: "metric", Fields: map[string]interface{}{"value": 1.5, "count": int64(3)}, Tags: map[string]string{"host": "host1", "warn": "80", "up": "true", "ciid": "CI-1"}, Timestamp: t1},
+				converted[0].Points[3],
+			}}},
+		},
+	}
+	for _, test := range tests {
+		input := copyPointGroups(test.input)
+		actual, err := PreparePointGroups(test.input, test.cfg, nil, logrus.StandardLogger())
+		if test.expectedErr != "" {
+			assert.EqualError(t, err, test.expectedErr, test.name)
+			continue
+		}
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.expected, actual, test.name)
+		// the input points are not modified
+		assert.Equal(t, input, test.input, test.name)
+	}
+}
+
+func TestNewProcessorsInvalid(t *testing.T) {
+	tests := []struct {
+		processor config.ProcessorConfig
+		expected  string
+	}{
+		{config.ProcessorConfig{Type: "rename", Rewrites: []config.ValueRewrite{{Tag: "host"}}}, "Invalid processor \"rename\": rewrite without pattern"},
+		{config.ProcessorConfig{Type: "convert", TagsToFields: map[string]string{"warn": "double"}}, "Invalid processor \"convert\": unknown field type \"double\" for tag \"warn\""},
+	}
+	for _, test := range tests {
+		_, err := NewProcessors([]config.ProcessorConfig{test.processor})
+		assert.EqualError(t, err, test.expected)
+	}
+
+	var processors []config.ProcessorConfig
+	err := json.Unmarshal([]byte(`[{"type": "rename", "rewrites": [{"tag": "host", "pattern": "("}]}]`), &processors)
+	assert.EqualError(t, err, "invalid regular expression \"(\": error parsing regexp: missing closing ): `(`")
+}
+
+func TestOutputProcessors(t *testing.T) {
+	created := 0
+	RegisterProcessor("counted", func(c config.ProcessorConfig) (Processor, error) {
+		created++
+		return ignoreProcessor{}, nil
+	})
+
+	cfg := config.OutputInflux{
+		Processors: []config.ProcessorConfig{{Type: "counted"}},
+		Measurements: map[string]config.MeasurementInflux{
+			"metric1": {},
+			"metric2": {},
+		},
+	}
+	processors, err := NewOutputProcessors(&cfg)
+	assert.Nil(t, err)
+	// once for the validation of the output processors and once per measurement
+	assert.Equal(t, 3, created)
+
+	// the chains are reused for every write
+	pointGroups := []PointGroup{{Measurement: "metric1", Points: []Point{{Measurement: "metric1", Fields: map[string]interface{}{"value": 1.0}}}}}
+	for i := 0; i < 2; i++ {
+		actual, err := processors.Prepare(pointGroups, nil, logrus.StandardLogger())
+		assert.Nil(t, err)
+		assert.Equal(t, pointGroups, actual)
+	}
+	assert.Equal(t, 3, created)
+}
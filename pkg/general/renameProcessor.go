@@ -0,0 +1,88 @@
+package general
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/max-bytes/metrics-receiver/pkg/config"
+)
+
+// renameProcessor renames tag keys, field keys and the measurement and rewrites tag and field values. Renamed points are regrouped
+// under their new measurement by OutputProcessors.Prepare.
+type renameProcessor struct {
+	tags        []keyRename
+	fields      []keyRename
+	measurement string
+	rewrites    []config.ValueRewrite
+}
+
+type keyRename struct {
+	from string
+	to   string
+}
+
+func newRenameProcessor(c config.ProcessorConfig) (Processor, error) {
+	for _, rewrite := range c.Rewrites {
+		if rewrite.Pattern == nil || rewrite.Pattern.Regexp == nil {
+			return nil, errors.New("rewrite without pattern")
+		}
+		if (rewrite.Tag == "") == (rewrite.Field == "") {
+			return nil, errors.New("rewrite needs either a tag or a field")
+		}
+	}
+	return &renameProcessor{
+		tags:        sortedKeyRenames(c.Tags),
+		fields:      sortedKeyRenames(c.Fields),
+		measurement: c.Measurement,
+		rewrites:    c.Rewrites,
+	}, nil
+}
+
+// sortedKeyRenames sorts the renames by their old key, so that the result is deterministic if several keys are renamed to the same key
+func sortedKeyRenames(renames map[string]string) []keyRename {
+	ret := make([]keyRename, 0, len(renames))
+	for from, to := range renames {
+		ret = append(ret, keyRename{from, to})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].from < ret[j].from })
+	return ret
+}
+
+func (p *renameProcessor) Process(points []Point, ctx *ProcessorContext) ([]Point, error) {
+	ret := make([]Point, 0, len(points))
+	for _, point := range points {
+		tags := CopyTags(point.Tags)
+		fields := CopyFields(point.Fields)
+
+		for _, r := range p.tags {
+			if v, ok := tags[r.from]; ok {
+				delete(tags, r.from)
+				tags[r.to] = v
+			}
+		}
+		for _, r := range p.fields {
+			if v, ok := fields[r.from]; ok {
+				delete(fields, r.from)
+				fields[r.to] = v
+			}
+		}
+
+		for _, rewrite := range p.rewrites {
+			if rewrite.Tag != "" {
+				if v, ok := tags[rewrite.Tag]; ok {
+					tags[rewrite.Tag] = rewrite.Pattern.ReplaceAllString(v, rewrite.Replacement)
+				}
+			} else if v, ok := fields[rewrite.Field].(string); ok {
+				fields[rewrite.Field] = rewrite.Pattern.ReplaceAllString(v, rewrite.Replacement)
+			}
+		}
+
+		if p.measurement != "" {
+			point.Measurement = p.measurement
+		}
+		point.Tags = tags
+		point.Fields = fields
+		ret = append(ret, point)
+	}
+	return ret, nil
+}
@@ -0,0 +1,251 @@
+package graphite
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/max-bytes/metrics-receiver/pkg/listener"
+)
+
+const defaultSeparator = "."
+const defaultField = "value"
+
+// Parser converts graphite plaintext lines ("path.to.metric value timestamp") into points using templates that map
+// the path segments to measurement, tags and field
+type Parser struct {
+	separator       string
+	templates       []template
+	defaultTemplate template
+}
+
+// template describes how the segments of a metric path are mapped, e.g. "host.measurement.field*";
+// filter (optional) restricts the paths the template is used for, e.g. "servers.*.cpu"
+type template struct {
+	filter []string
+	parts  []string
+	tags   map[string]string
+}
+
+// NewParser creates a parser from template definitions of the form "[filter] template [tag1=value1,tag2=value2]".
+// A template part is "measurement", "field", a tag name or empty (segment is skipped); the last part can be "measurement*" or "field*"
+// to consume all remaining segments. The first template whose filter matches the path is used; paths that match no filter are parsed
+// with the template without filter or "measurement*"
+func NewParser(separator string, templates []string) (*Parser, error) {
+	if separator == "" {
+		separator = defaultSeparator
+	}
+
+	p := &Parser{
+		separator:       separator,
+		defaultTemplate: template{parts: []string{"measurement*"}},
+	}
+
+	for _, definition := range templates {
+		t, err := parseTemplate(definition)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid graphite template \"%s\": %w", definition, err)
+		}
+		if t.filter == nil {
+			p.defaultTemplate = t
+		} else {
+			p.templates = append(p.templates, t)
+		}
+	}
+
+	return p, nil
+}
+
+func parseTemplate(definition string) (template, error) {
+	tokens := strings.Fields(definition)
+
+	var filter, pattern, tags string
+	switch len(tokens) {
+	case 1:
+		pattern = tokens[0]
+	case 2:
+		if strings.Contains(tokens[1], "=") {
+			pattern, tags = tokens[0], tokens[1]
+		} else {
+			filter, pattern = tokens[0], tokens[1]
+		}
+	case 3:
+		filter, pattern, tags = tokens[0], tokens[1], tokens[2]
+	default:
+		return template{}, errors.New("expected \"[filter] template [tags]\"")
+	}
+
+	t := template{parts: strings.Split(pattern, "."), tags: make(map[string]string)}
+	if filter != "" {
+		t.filter = strings.Split(filter, ".")
+	}
+
+	hasMeasurement := false
+	for i, part := range t.parts {
+		if (part == "measurement*" || part == "field*") && i != len(t.parts)-1 {
+			return template{}, fmt.Errorf("\"%s\" is only allowed as last part", part)
+		}
+		if part == "measurement" || part == "measurement*" {
+			hasMeasurement = true
+		}
+	}
+	if !hasMeasurement {
+		return template{}, errors.New("no measurement part specified")
+	}
+
+	if tags != "" {
+		for _, kv := range strings.Split(tags, ",") {
+			split := strings.SplitN(kv, "=", 2)
+			if len(split) != 2 || split[0] == "" || split[1] == "" {
+				return template{}, fmt.Errorf("invalid tag \"%s\"", kv)
+			}
+			t.tags[split[0]] = split[1]
+		}
+	}
+
+	return t, nil
+}
+
+// matches reports whether the filter matches the segments of a path; "*" matches any single segment and the
+// path may have more segments than the filter
+func (t *template) matches(segments []string) bool {
+	if len(segments) < len(t.filter) {
+		return false
+	}
+	for i, f := range t.filter {
+		if f != "*" && f != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) findTemplate(segments []string) *template {
+	for i := range p.templates {
+		if p.templates[i].matches(segments) {
+			return &p.templates[i]
+		}
+	}
+	return &p.defaultTemplate
+}
+
+// Parse parses all lines of a graphite plaintext payload; lines that can not be parsed are skipped and returned as errors
+func (p *Parser) Parse(input []byte, currentTime time.Time) ([]general.Point, []error) {
+	var ret []general.Point
+	var errs []error
+
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	// lines as long as the stream listeners accept
+	scanner.Buffer(nil, listener.MaxLineSize+1)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		point, err := p.ParseLine(line, currentTime)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNumber, err))
+			continue
+		}
+		ret = append(ret, point)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return ret, errs
+}
+
+// ParseLine parses a single line "path[;tag=value...] value [timestamp]"; the timestamp is in (fractional) seconds
+func (p *Parser) ParseLine(line string, currentTime time.Time) (general.Point, error) {
+	tokens := strings.Fields(line)
+	if len(tokens) != 2 && len(tokens) != 3 {
+		return general.Point{}, errors.New("expected \"path value [timestamp]\"")
+	}
+
+	// graphite 1.1 style tags are appended to the path, separated by semicolons
+	pathAndTags := strings.Split(tokens[0], ";")
+	path := pathAndTags[0]
+	if path == "" {
+		return general.Point{}, errors.New("missing metric path")
+	}
+
+	value, err := strconv.ParseFloat(tokens[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return general.Point{}, fmt.Errorf("invalid value \"%s\"", tokens[1])
+	}
+
+	timestamp := currentTime
+	if len(tokens) == 3 {
+		ts, err := strconv.ParseFloat(tokens[2], 64)
+		if err != nil || math.IsNaN(ts) || math.IsInf(ts, 0) {
+			return general.Point{}, fmt.Errorf("invalid timestamp \"%s\"", tokens[2])
+		}
+		// carbon treats negative timestamps as "now"
+		if ts >= 0 {
+			sec, frac := math.Modf(ts)
+			timestamp = time.Unix(int64(sec), int64(frac*float64(time.Second)))
+		}
+	}
+
+	segments := strings.Split(path, ".")
+	t := p.findTemplate(segments)
+
+	tags := make(map[string]string)
+	for k, v := range t.tags {
+		tags[k] = v
+	}
+	var measurement, field []string
+	pathTags := make(map[string][]string)
+	for i, part := range t.parts {
+		if i >= len(segments) {
+			break
+		}
+		switch part {
+		case "":
+		case "measurement":
+			measurement = append(measurement, segments[i])
+		case "measurement*":
+			measurement = append(measurement, segments[i:]...)
+		case "field":
+			field = append(field, segments[i])
+		case "field*":
+			field = append(field, segments[i:]...)
+		default:
+			pathTags[part] = append(pathTags[part], segments[i])
+		}
+	}
+	// multiple segments mapped to the same tag are joined
+	for k, v := range pathTags {
+		tags[k] = strings.Join(v, p.separator)
+	}
+
+	for _, tag := range pathAndTags[1:] {
+		split := strings.SplitN(tag, "=", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return general.Point{}, fmt.Errorf("invalid tag \"%s\"", tag)
+		}
+		tags[split[0]] = split[1]
+	}
+
+	if len(measurement) == 0 {
+		return general.Point{}, fmt.Errorf("no measurement found in path \"%s\"", path)
+	}
+	fieldName := defaultField
+	if len(field) > 0 {
+		fieldName = strings.Join(field, p.separator)
+	}
+
+	return general.Point{
+		Measurement: strings.Join(measurement, p.separator),
+		Fields:      map[string]interface{}{fieldName: value},
+		Tags:        tags,
+		Timestamp:   timestamp,
+	}, nil
+}
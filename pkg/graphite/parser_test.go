@@ -0,0 +1,110 @@
+package graphite
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultTemplate(t *testing.T) {
+	parser, err := NewParser("", nil)
+	assert.Nil(t, err)
+
+	lines := []string{
+		"servers.host1.cpu.load 0.5 1613985840",
+		"servers.host1.cpu.load;dc=vienna 1.5 1613985840.5",
+		"",
+		"servers.host1.cpu.load 2",
+	}
+
+	currentTime := time.Now()
+	actual, errs := parser.Parse([]byte(strings.Join(lines, "\n")), currentTime)
+	assert.Empty(t, errs)
+
+	expected := []general.Point{
+		{Measurement: "servers.host1.cpu.load", Fields: map[string]interface{}{"value": 0.5}, Tags: map[string]string{}, Timestamp: time.Unix(1613985840, 0)},
+		{Measurement: "servers.host1.cpu.load", Fields: map[string]interface{}{"value": 1.5}, Tags: map[string]string{"dc": "vienna"}, Timestamp: time.Unix(1613985840, 500000000)},
+		{Measurement: "servers.host1.cpu.load", Fields: map[string]interface{}{"value": 2.0}, Tags: map[string]string{}, Timestamp: currentTime},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplates(t *testing.T) {
+	parser, err := NewParser("_", []string{
+		"servers.* .host.measurement.field*",
+		"apps.*.*.* .app.env.measurement region=eu,team=ops",
+		"network.dc.* ..host.measurement*",
+		"host.host.measurement*",
+	})
+	assert.Nil(t, err)
+
+	lines := []string{
+		"servers.host1.cpu.load.shortterm 0.5 1613985840",
+		"apps.shop.prod.requests 100 1613985840",
+		"network.dc.switch1.if.eth0.rx 42 1613985840",
+		"some.other.metric.name 1 1613985840",
+	}
+
+	actual, errs := parser.Parse([]byte(strings.Join(lines, "\n")), time.Now())
+	assert.Empty(t, errs)
+
+	expected := []general.Point{
+		{Measurement: "cpu", Fields: map[string]interface{}{"load_shortterm": 0.5}, Tags: map[string]string{"host": "host1"}, Timestamp: time.Unix(1613985840, 0)},
+		{Measurement: "requests", Fields: map[string]interface{}{"value": 100.0}, Tags: map[string]string{"app": "shop", "env": "prod", "region": "eu", "team": "ops"}, Timestamp: time.Unix(1613985840, 0)},
+		{Measurement: "if_eth0_rx", Fields: map[string]interface{}{"value": 42.0}, Tags: map[string]string{"host": "switch1"}, Timestamp: time.Unix(1613985840, 0)},
+		{Measurement: "metric_name", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "some_other"}, Timestamp: time.Unix(1613985840, 0)},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestInvalidTemplates(t *testing.T) {
+	invalidTemplates := []string{
+		"host.field",                   // no measurement
+		"measurement*.host",            // greedy part not at the end
+		"servers.* measurement foo",    // invalid tags
+		"a b c d",                      // too many tokens
+		"servers.* measurement a=b,=c", // invalid tags
+	}
+
+	for _, template := range invalidTemplates {
+		_, err := NewParser("", []string{template})
+		assert.NotNil(t, err, "Expected error for template: %s", template)
+	}
+}
+
+func TestInvalidLines(t *testing.T) {
+	parser, err := NewParser("", []string{"servers.* .host.measurement"})
+	assert.Nil(t, err)
+
+	lines := []string{
+		"servers.host1.cpu",                  // no value
+		"servers.host1.cpu abc 1613985840",   // invalid value
+		"servers.host1.cpu NaN 1613985840",   // invalid value
+		"servers.host1.cpu 1 abc",            // invalid timestamp
+		"servers.host1.cpu 1 1613985840 foo", // too many tokens
+		"servers.host1.cpu;dc 1 1613985840",  // invalid tag
+		"servers.host1 1 1613985840",         // no measurement segment
+		"servers.host1.cpu 1 1613985840",     // valid
+	}
+
+	actual, errs := parser.Parse([]byte(strings.Join(lines, "\n")), time.Now())
+	assert.Len(t, errs, 7)
+	assert.Len(t, actual, 1)
+}
+
+func TestLongLines(t *testing.T) {
+	parser, err := NewParser("", nil)
+	assert.Nil(t, err)
+
+	// lines longer than the default token size of bufio.Scanner don't end the parsing
+	lines := []string{
+		strings.Repeat("a", 100*1024) + " 1 1613985840",
+		"servers.host1.cpu 1 1613985840",
+	}
+	actual, errs := parser.Parse([]byte(strings.Join(lines, "\n")), time.Now())
+	assert.Len(t, errs, 0)
+	assert.Len(t, actual, 2)
+}
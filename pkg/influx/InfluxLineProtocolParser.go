@@ -1,248 +1,494 @@
 package influx
 
 import (
+	"bytes"
 	"errors"
-	"regexp"
+	"fmt"
+	"io"
+	"math"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/max-bytes/metrics-receiver/pkg/general"
 )
 
-func Parse(input string, currentTimestamp time.Time) ([]general.Point, error) {
+// ParsePrecision converts the precision parameter of influx write requests (v1: n, ns, u, ms, s, m, h; v2: ns, us, ms, s)
+// into the duration of one timestamp unit; an empty precision means nanoseconds
+func ParsePrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µ", "µs":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("Unknown precision \"%s\"", precision)
+	}
+}
+
+// LineError describes a line of a line protocol payload that could not be parsed
+type LineError struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
 
-	input = strings.ReplaceAll(input, "\r", "")
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// ParseErrors is returned by Parse if one or more lines could not be parsed
+type ParseErrors []LineError
 
-	lines := strings.Split(input, "\n")
-	var ret []general.Point = make([]general.Point, 0, len(lines))
+func (e ParseErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d lines could not be parsed, first error: %s", len(e), e[0].Error())
+}
 
-	for _, line := range lines {
-		if line == "" {
+// Parse parses a complete line protocol payload; empty lines and comments are skipped, points without timestamp get currentTimestamp.
+// Invalid lines do not stop parsing: all valid points are returned and the invalid lines are reported in a ParseErrors error
+func Parse(input []byte, currentTimestamp time.Time, precision time.Duration) ([]general.Point, error) {
+	p := parser{buf: input, currentTime: currentTimestamp, precision: precision}
+	ret := make([]general.Point, 0, 16)
+	var lineErrors ParseErrors
+
+	line := 1
+	lineStart := 0
+	for !p.eof() {
+		// string field values may contain newlines, so the line number is derived from the newlines consumed so far
+		line += bytes.Count(p.buf[lineStart:p.pos], []byte{'\n'})
+		lineStart = p.pos
+
+		point, ok, err := p.parseLine()
+		if err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Reason: err.Error()})
 			continue
 		}
+		if ok {
+			ret = append(ret, point)
+		}
+	}
 
-		if line[0] == '#' {
-			continue
+	if len(lineErrors) > 0 {
+		return ret, lineErrors
+	}
+	return ret, nil
+}
+
+// size of the chunks read by ParseStream
+const streamChunkSize = 64 * 1024
+
+// maximum length of a single line in ParseStream; longer lines would have to be buffered completely
+const maxStreamLineSize = 4 * 1024 * 1024
+
+// ParseStream parses line protocol read from r chunk by chunk, so that the input never has to be held in memory as a whole.
+// The points are passed to handle in batches of at most batchSize points; an error returned by handle stops parsing and is
+// returned as is. Like Parse, invalid lines do not stop parsing and are reported in a ParseErrors error at the end
+func ParseStream(r io.Reader, currentTimestamp time.Time, precision time.Duration, batchSize int, handle func(points []general.Point) error) error {
+	buf := make([]byte, 0, streamChunkSize)
+	batch := make([]general.Point, 0, batchSize)
+	var lineErrors ParseErrors
+	line := 1
+	atEOF := false
+
+	for !atEOF {
+		if cap(buf)-len(buf) < streamChunkSize {
+			if len(buf) > maxStreamLineSize {
+				return fmt.Errorf("Line %d exceeds the maximum line length of %d bytes", line, maxStreamLineSize)
+			}
+			grown := make([]byte, len(buf), 2*cap(buf)+streamChunkSize)
+			copy(grown, buf)
+			buf = grown
+		}
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			atEOF = true
+		} else if err != nil {
+			return err
 		}
 
-		point, error := ParsePoint(line, currentTimestamp)
+		p := parser{buf: buf, currentTime: currentTimestamp, precision: precision}
+		lineStart := 0
+		for !p.eof() {
+			start := p.pos
+			point, ok, err := p.parseLine()
+			// a line that ends at the end of the buffer might not be complete yet (even if it parsed), so it is parsed
+			// again once more data was read
+			if p.eof() && !atEOF {
+				p.pos = start
+				break
+			}
+
+			line += bytes.Count(p.buf[lineStart:start], []byte{'\n'})
+			lineStart = start
 
-		if error != nil {
-			return nil, error
+			if err != nil {
+				lineErrors = append(lineErrors, LineError{Line: line, Reason: err.Error()})
+				continue
+			}
+			if ok {
+				batch = append(batch, point)
+				if len(batch) >= batchSize {
+					if err := handle(batch); err != nil {
+						return err
+					}
+					batch = make([]general.Point, 0, batchSize)
+				}
+			}
 		}
 
-		ret = append(ret, point)
+		// keep the unparsed rest for the next chunk; the newlines before it were already counted
+		line += bytes.Count(p.buf[lineStart:p.pos], []byte{'\n'})
+		buf = buf[:copy(buf, buf[p.pos:])]
 	}
 
-	return ret, nil
+	if len(batch) > 0 {
+		if err := handle(batch); err != nil {
+			return err
+		}
+	}
+
+	if len(lineErrors) > 0 {
+		return lineErrors
+	}
+	return nil
 }
 
-const ESCAPEDSPACE = "___ESCSPC___"
-const ESCAPEDCOMMA = "___ESCCMA___"
-const ESCAPEDEQUAL = "___ESCEQL___" // MODIFICATION
-const ESCAPEDDBLQUOTE = "___ESCDBQ___"
-const ESCAPEDBACKSLASH = "___ESCBKS___"
-const ESCAPEDSTRINGPREFIX = "___ESCSTR_"
-
-// var regexEscapedSpaceForward = regexp.MustCompile(`\\ `)
-// var regexEscapedCommaForward = regexp.MustCompile(`\\,`)
-// var regexEscapedEqualForward = regexp.MustCompile(`\\=`)
-// var regexEscapedDoubleQuoteForward = regexp.MustCompile(`\\\"`)
-// var regexEscapedBackslashForward = regexp.MustCompile(`\\\\`)
-// var regexEscapedSpaceBackward = regexp.MustCompile(fmt.Sprintf("%v", ESCAPEDSPACE))
-// var regexEscapedCommaBackward = regexp.MustCompile(fmt.Sprintf("%v", ESCAPEDCOMMA))
-// var regexEscapedEqualBackward = regexp.MustCompile(fmt.Sprintf("%v", ESCAPEDEQUAL))
-// var regexEscapedDoubleQuoteBackward = regexp.MustCompile(fmt.Sprintf("%v", ESCAPEDDBLQUOTE))
-// var regexEscapedBackslashBackward = regexp.MustCompile(fmt.Sprintf("%v", ESCAPEDBACKSLASH))
-var regexLineVariant1 = regexp.MustCompile("^(.*?) (.*) (.*)$")
-var regexLineVariant2 = regexp.MustCompile("^(.*?) (.*)$")
-var regexInt = regexp.MustCompile(`(\d+)[ui]`)
-var regexEscapedQuotedStringForward = regexp.MustCompile(`"(.*?)"`)
-var regexEscapedQuotedStringBackward = regexp.MustCompile(ESCAPEDSTRINGPREFIX + `(\d+)___`)
-
-func ParsePoint(line string, currentTime time.Time) (general.Point, error) {
+// ParsePoint parses a single line of line protocol
+func ParsePoint(line []byte, currentTime time.Time, precision time.Duration) (general.Point, error) {
+	p := parser{buf: line, currentTime: currentTime, precision: precision}
+	point, ok, err := p.parseLine()
+	if err != nil {
+		return general.Point{}, err
+	}
+	if !ok {
+		return general.Point{}, errors.New("empty line")
+	}
+	if !p.eof() {
+		return general.Point{}, errors.New("unexpected data after end of line")
+	}
+	return point, nil
+}
+
+// parser is a single-pass parser over a line protocol buffer; pos always points at the next unconsumed byte
+type parser struct {
+	buf         []byte
+	pos         int
+	currentTime time.Time
+	precision   time.Duration
+}
 
-	line = strings.ReplaceAll(line, "\\ ", ESCAPEDSPACE)
-	line = strings.ReplaceAll(line, "\\,", ESCAPEDCOMMA)
-	line = strings.ReplaceAll(line, "\\=", ESCAPEDEQUAL) // MODIFICATION
-	line = strings.ReplaceAll(line, "\\\"", ESCAPEDDBLQUOTE)
-	line = strings.ReplaceAll(line, "\\\\", ESCAPEDBACKSLASH)
-	// line = regexEscapedSpaceForward.ReplaceAllString(line, ESCAPEDSPACE)
-	// line = regexEscapedCommaForward.ReplaceAllString(line, ESCAPEDCOMMA)
-	// line = regexEscapedEqualForward.ReplaceAllString(line, ESCAPEDEQUAL) // MODIFICATION
-	// line = regexEscapedDoubleQuoteForward.ReplaceAllString(line, ESCAPEDDBLQUOTE)
-	// line = regexEscapedBackslashForward.ReplaceAllString(line, ESCAPEDBACKSLASH)
+func (p *parser) eof() bool {
+	return p.pos >= len(p.buf)
+}
 
-	measurementAndTagsStr := ""
-	fieldSetStr := ""
-	timestampStr := ""
+// atLineEnd reports whether the parser is at the end of the current line (newline, CRLF or end of input)
+func (p *parser) atLineEnd() bool {
+	if p.eof() || p.buf[p.pos] == '\n' {
+		return true
+	}
+	return p.buf[p.pos] == '\r' && (p.pos+1 == len(p.buf) || p.buf[p.pos+1] == '\n')
+}
 
-	if tokens := regexLineVariant1.FindStringSubmatch(line); tokens != nil {
-		lenTokens := len(tokens)
-		if lenTokens > 1 {
-			measurementAndTagsStr = tokens[1]
+// skipSpaces skips the separators between measurement/tags, fields and timestamp
+func (p *parser) skipSpaces() {
+	for !p.eof() && p.buf[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// skipWhitespace skips leading and trailing whitespace of a line
+func (p *parser) skipWhitespace() {
+	for !p.eof() && (p.buf[p.pos] == ' ' || p.buf[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine consumes everything up to and including the next newline
+func (p *parser) skipLine() {
+	for !p.eof() && p.buf[p.pos] != '\n' {
+		p.pos++
+	}
+	if !p.eof() {
+		p.pos++
+	}
+}
+
+// parseLine parses the next line; ok is false for empty and comment lines, on error the rest of the line is skipped
+func (p *parser) parseLine() (point general.Point, ok bool, err error) {
+	p.skipWhitespace()
+	if p.atLineEnd() {
+		p.skipLine()
+		return general.Point{}, false, nil
+	}
+	if p.buf[p.pos] == '#' {
+		p.skipLine()
+		return general.Point{}, false, nil
+	}
+
+	point, err = p.parsePoint()
+	if err != nil {
+		p.skipLine()
+		return general.Point{}, false, err
+	}
+
+	// only trailing whitespace is allowed after the timestamp
+	p.skipWhitespace()
+	if !p.atLineEnd() {
+		p.skipLine()
+		return general.Point{}, false, errors.New("unexpected data after timestamp")
+	}
+	p.skipLine()
+
+	return point, true, nil
+}
+
+func (p *parser) parsePoint() (general.Point, error) {
+	measurement, err := p.scanIdentifier(isMeasurementEnd)
+	if err != nil {
+		return general.Point{}, err
+	}
+	if measurement == "" {
+		return general.Point{}, errors.New("missing measurement")
+	}
+
+	tags := make(map[string]string)
+	for !p.eof() && p.buf[p.pos] == ',' {
+		p.pos++
+		key, err := p.scanIdentifier(isTagKeyEnd)
+		if err != nil {
+			return general.Point{}, err
+		}
+		if key == "" {
+			return general.Point{}, errors.New("missing tag key")
 		}
-		if lenTokens > 2 {
-			fieldSetStr = tokens[2]
-		}
-		if lenTokens > 3 {
-			timestampStr = tokens[3]
-		}
-	} else if tokens := regexLineVariant2.FindStringSubmatch(line); tokens != nil {
-		lenTokens := len(tokens)
-		if lenTokens > 1 {
-			measurementAndTagsStr = tokens[1]
-		}
-		if lenTokens > 2 {
-			fieldSetStr = tokens[2]
-		}
-		if lenTokens > 3 {
-			timestampStr = tokens[3]
-		}
-	} else {
-		// invalid number of tokens
-		return general.Point{}, errors.New("invalid number of tokens")
-	}
-
-	measurementAndTags := strings.Split(measurementAndTagsStr, ",")
-
-	measurement := ArrayShift(&measurementAndTags)
-
-	measurement = strings.ReplaceAll(measurement, ESCAPEDSPACE, " ")
-	measurement = strings.ReplaceAll(measurement, ESCAPEDCOMMA, ",")
-	measurement = strings.ReplaceAll(measurement, ESCAPEDEQUAL, "=")
-	measurement = strings.ReplaceAll(measurement, ESCAPEDDBLQUOTE, "\"")
-	// measurement = regexEscapedSpaceBackward.ReplaceAllString(measurement, " ")
-	// measurement = regexEscapedCommaBackward.ReplaceAllString(measurement, ",")
-	// measurement = regexEscapedEqualBackward.ReplaceAllString(measurement, "=")
-	// measurement = regexEscapedDoubleQuoteBackward.ReplaceAllString(measurement, "\"")
-
-	tagsStr := measurementAndTags
-
-	tagSet := make(map[string]string)
-
-	for _, tagStr := range tagsStr {
-
-		tagStr = strings.ReplaceAll(tagStr, ESCAPEDSPACE, " ")
-		tagStr = strings.ReplaceAll(tagStr, ESCAPEDCOMMA, ",")
-		tagStr = strings.ReplaceAll(tagStr, ESCAPEDDBLQUOTE, "\"")
-		tagStr = strings.ReplaceAll(tagStr, ESCAPEDBACKSLASH, "\\")
-		// tagStr = regexEscapedSpaceBackward.ReplaceAllString(tagStr, " ")
-		// tagStr = regexEscapedCommaBackward.ReplaceAllString(tagStr, ",")
-		// tagStr = regexEscapedDoubleQuoteBackward.ReplaceAllString(tagStr, "\"")
-		// tagStr = regexEscapedBackslashBackward.ReplaceAllString(tagStr, "\\")
-
-		tagKV := strings.Split(tagStr, "=")
-
-		if len(tagKV) == 2 {
-			tagKey := tagKV[0]
-			tagValue := tagKV[1]
-
-			tagKey = strings.ReplaceAll(tagKey, ESCAPEDEQUAL, "=")
-			tagValue = strings.ReplaceAll(tagValue, ESCAPEDEQUAL, "=")
-			// tagKey = regexEscapedEqualBackward.ReplaceAllString(tagKey, "=")
-			// tagValue = regexEscapedEqualBackward.ReplaceAllString(tagValue, "=")
-
-			tagSet[tagKey] = tagValue
-		}
-
-	}
-
-	// cut out quoted strings and replace them with placeholders (will be inserted back in later)
-	var strs []string
-	quoteReplacementCount := 0
-	if strings.Contains(fieldSetStr, `"`) {
-		fieldSetStr = regexEscapedQuotedStringForward.ReplaceAllStringFunc(fieldSetStr, func(matches string) string {
-			t := regexEscapedQuotedStringForward.FindStringSubmatch(fieldSetStr)
-			strs = append(strs, t[1])
-			result := ESCAPEDSTRINGPREFIX + strconv.Itoa(quoteReplacementCount) + `___`
-			quoteReplacementCount = quoteReplacementCount + 1
-			return result
-		})
+		if p.eof() || p.buf[p.pos] != '=' {
+			return general.Point{}, fmt.Errorf("missing tag value for tag key \"%s\"", key)
+		}
+		p.pos++
+		value, err := p.scanIdentifier(isTagValueEnd)
+		if err != nil {
+			return general.Point{}, err
+		}
+		if value == "" {
+			return general.Point{}, fmt.Errorf("missing tag value for tag key \"%s\"", key)
+		}
+		tags[key] = value
 	}
 
-	fieldSetArray := strings.Split(fieldSetStr, ",")
-	fieldSet := make(map[string]interface{})
+	if p.atLineEnd() {
+		return general.Point{}, errors.New("missing fields")
+	}
+	p.skipSpaces()
+	if p.atLineEnd() {
+		return general.Point{}, errors.New("missing fields")
+	}
 
-	for _, fieldStr := range fieldSetArray {
+	fields := make(map[string]interface{})
+	for {
+		key, err := p.scanIdentifier(isFieldKeyEnd)
+		if err != nil {
+			return general.Point{}, err
+		}
+		if key == "" {
+			return general.Point{}, errors.New("missing field key")
+		}
+		if p.eof() || p.buf[p.pos] != '=' {
+			return general.Point{}, fmt.Errorf("missing field value for field key \"%s\"", key)
+		}
+		p.pos++
+		value, err := p.scanFieldValue()
+		if err != nil {
+			return general.Point{}, fmt.Errorf("invalid field \"%s\": %w", key, err)
+		}
+		fields[key] = value
 
-		fieldStr = strings.ReplaceAll(fieldStr, ESCAPEDSPACE, " ")
-		fieldStr = strings.ReplaceAll(fieldStr, ESCAPEDCOMMA, ",")
-		// fieldStr = regexEscapedSpaceBackward.ReplaceAllString(fieldStr, " ")
-		// fieldStr = regexEscapedCommaBackward.ReplaceAllString(fieldStr, ",")
+		if p.eof() || p.buf[p.pos] != ',' {
+			break
+		}
+		p.pos++
+	}
 
-		fieldKV := strings.Split(fieldStr, "=")
+	timestamp := p.currentTime
+	if !p.atLineEnd() {
+		if p.buf[p.pos] != ' ' {
+			return general.Point{}, fmt.Errorf("unexpected character '%c' after field set", p.buf[p.pos])
+		}
+		p.skipSpaces()
+		if !p.atLineEnd() {
+			timestamp, err = p.scanTimestamp()
+			if err != nil {
+				return general.Point{}, err
+			}
+		}
+	}
 
-		if len(fieldKV) == 2 {
-			key := fieldKV[0]
-			value := fieldKV[1]
+	return general.Point{Measurement: measurement, Fields: fields, Tags: tags, Timestamp: timestamp}, nil
+}
 
-			// insert previously cut out quoted strings again
-			if quoteReplacementCount > 0 {
-				if strings.Contains(key, ESCAPEDSTRINGPREFIX) {
-					key = regexEscapedQuotedStringBackward.ReplaceAllStringFunc(key, func(matches string) string {
-						t := regexEscapedQuotedStringBackward.FindStringSubmatch(key)
-						index, _ := strconv.Atoi(t[1])
-						return "\"" + strs[index] + "\""
-					})
-				}
-				if strings.Contains(value, ESCAPEDSTRINGPREFIX) {
-					value = regexEscapedQuotedStringBackward.ReplaceAllStringFunc(value, func(matches string) string {
-						t := regexEscapedQuotedStringBackward.FindStringSubmatch(value)
-						index, _ := strconv.Atoi(t[1])
-						return strs[index]
-					})
-				}
+func isMeasurementEnd(c byte) bool { return c == ',' || c == ' ' }
+func isTagKeyEnd(c byte) bool      { return c == '=' || c == ',' || c == ' ' }
+func isTagValueEnd(c byte) bool    { return c == ',' || c == ' ' }
+func isFieldKeyEnd(c byte) bool    { return c == '=' || c == ',' || c == ' ' }
+
+// isEscapable reports whether a backslash in front of c is treated as escape character;
+// in front of any other character the backslash is taken literally
+func isEscapable(c byte) bool {
+	return c == ',' || c == ' ' || c == '=' || c == '"' || c == '\\'
+}
+
+// isStringEscapable is the counterpart of isEscapable for string field values, in which only quotes and backslashes are escaped
+// (e.g. "a\,b" is the string a\,b)
+func isStringEscapable(c byte) bool {
+	return c == '"' || c == '\\'
+}
+
+// scanIdentifier scans a measurement, tag key/value or field key up to the first unescaped character for which isEnd returns true
+// (or the end of the line) and returns it unescaped
+func (p *parser) scanIdentifier(isEnd func(c byte) bool) (string, error) {
+	start := p.pos
+	escaped := false
+	for !p.atLineEnd() {
+		c := p.buf[p.pos]
+		if c == '\\' && p.pos+1 < len(p.buf) && isEscapable(p.buf[p.pos+1]) {
+			escaped = true
+			p.pos += 2
+			continue
+		}
+		if isEnd(c) {
+			break
+		}
+		p.pos++
+	}
+
+	if !escaped {
+		return string(p.buf[start:p.pos]), nil
+	}
+	return unescape(p.buf[start:p.pos], isEscapable), nil
+}
+
+func unescape(b []byte, escapable func(c byte) bool) string {
+	ret := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '\\' && i+1 < len(b) && escapable(b[i+1]) {
+			i++
+		}
+		ret = append(ret, b[i])
+	}
+	return string(ret)
+}
+
+func (p *parser) scanFieldValue() (interface{}, error) {
+	if p.atLineEnd() {
+		return nil, errors.New("missing value")
+	}
+
+	// string value; may contain any character (including newlines), only quotes and backslashes must be escaped
+	if p.buf[p.pos] == '"' {
+		p.pos++
+		start := p.pos
+		escaped := false
+		for {
+			if p.eof() {
+				return nil, errors.New("unterminated string value")
 			}
+			c := p.buf[p.pos]
+			if c == '\\' && p.pos+1 < len(p.buf) && isStringEscapable(p.buf[p.pos+1]) {
+				escaped = true
+				p.pos += 2
+				continue
+			}
+			if c == '"' {
+				break
+			}
+			p.pos++
+		}
+		value := p.buf[start:p.pos]
+		p.pos++ // closing quote
 
-			key = strings.ReplaceAll(key, ESCAPEDEQUAL, "=")
-			value = strings.ReplaceAll(value, ESCAPEDEQUAL, "=")
-			key = strings.ReplaceAll(key, ESCAPEDDBLQUOTE, "\"")
-			value = strings.ReplaceAll(value, ESCAPEDDBLQUOTE, "\"")
-			key = strings.ReplaceAll(key, ESCAPEDBACKSLASH, "\\")
-			value = strings.ReplaceAll(value, ESCAPEDBACKSLASH, "\\")
-			// key = regexEscapedEqualBackward.ReplaceAllString(key, "=")
-			// value = regexEscapedEqualBackward.ReplaceAllString(value, "=")
-			// key = regexEscapedDoubleQuoteBackward.ReplaceAllString(key, "\"")
-			// value = regexEscapedDoubleQuoteBackward.ReplaceAllString(value, "\"")
-			// value = regexEscapedBackslashBackward.ReplaceAllString(value, "\\")
-			// key = regexEscapedBackslashBackward.ReplaceAllString(key, "\\")
-
-			// Try to convert the string to a float or integer
-			// TODO: handle booleans
-			if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
-				fieldSet[key] = floatVal
-			} else if m := regexInt.FindStringSubmatch(value); m != nil { // complex integer check using regex
-				v, e := strconv.ParseInt(m[1], 10, 64)
-				if e != nil {
-					return general.Point{}, e
-				}
-				fieldSet[key] = v
-			} else {
-				fieldSet[key] = value
+		if p.atLineEnd() || p.buf[p.pos] == ',' || p.buf[p.pos] == ' ' {
+			if escaped {
+				return unescape(value, isStringEscapable), nil
 			}
+			return string(value), nil
 		}
+		return nil, errors.New("unexpected character after string value")
 	}
 
-	// build a proper timestamp: parse if set, set to current time if not set
-	var timestamp time.Time
-	if timestampStr != "" {
-		t, _ := strconv.Atoi(timestampStr)
-		timestamp = time.Unix(0, int64(t))
-	} else {
-		timestamp = currentTime
+	start := p.pos
+	for !p.atLineEnd() && p.buf[p.pos] != ',' && p.buf[p.pos] != ' ' {
+		p.pos++
+	}
+	return parseFieldValue(p.buf[start:p.pos])
+}
+
+// parseFieldValue interprets an unquoted field value as integer (suffix i), unsigned integer (suffix u), boolean or float
+func parseFieldValue(token []byte) (interface{}, error) {
+	if len(token) == 0 {
+		return nil, errors.New("missing value")
 	}
 
-	return general.Point{Measurement: measurement, Fields: fieldSet, Tags: tagSet, Timestamp: timestamp}, nil
+	switch string(token) {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	last := token[len(token)-1]
+	switch last {
+	case 'i':
+		v, err := strconv.ParseInt(string(token[:len(token)-1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer value \"%s\"", token)
+		}
+		return v, nil
+	case 'u':
+		v, err := strconv.ParseUint(string(token[:len(token)-1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer value \"%s\"", token)
+		}
+		return v, nil
+	}
+
+	// strconv.ParseFloat also accepts things like "Inf", "NaN" or hex floats, which are not valid in line protocol
+	for _, c := range token {
+		if !(c >= '0' && c <= '9') && c != '.' && c != '-' && c != '+' && c != 'e' && c != 'E' {
+			return nil, fmt.Errorf("invalid value \"%s\"", token)
+		}
+	}
+	v, err := strconv.ParseFloat(string(token), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid float value \"%s\"", token)
+	}
+	return v, nil
 }
 
-func ArrayShift(s *[]string) string {
-	if len(*s) == 0 {
-		// return nil
-		return ""
+func (p *parser) scanTimestamp() (time.Time, error) {
+	start := p.pos
+	for !p.atLineEnd() && p.buf[p.pos] != ' ' {
+		p.pos++
+	}
+	token := string(p.buf[start:p.pos])
+
+	t, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp \"%s\"", token)
 	}
-	f := (*s)[0]
-	*s = (*s)[1:]
-	return f
+
+	unit := int64(p.precision)
+	if t > math.MaxInt64/unit || t < math.MinInt64/unit {
+		return time.Time{}, fmt.Errorf("timestamp \"%s\" out of range", token)
+	}
+
+	return time.Unix(0, t*unit), nil
 }
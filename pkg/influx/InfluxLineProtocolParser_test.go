@@ -1,8 +1,12 @@
 package influx
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/max-bytes/metrics-receiver/pkg/general"
@@ -18,11 +22,11 @@ func TestBasicFunctionality(t *testing.T) {
 		"weather2,location=us-midwest,source=test-source temperature=82,foo=12.3,bar=-1202.23 1465839830100400201"}
 
 	currentTime := time.Now()
-	actual, _ := Parse(strings.Join(lines, "\n"), currentTime)
+	actual, _ := Parse([]byte(strings.Join(lines, "\n")), currentTime, time.Nanosecond)
 
 	expected := []general.Point{
 		{Measurement: "weather", Fields: map[string]interface{}{"temperature": 82.0}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
-		{Measurement: "weather", Fields: map[string]interface{}{"temperature": int64(82)}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: currentTime}, // make this nil
+		{Measurement: "weather", Fields: map[string]interface{}{"temperature": uint64(82)}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: currentTime}, // make this nil
 		{Measurement: "weather2", Fields: map[string]interface{}{"temperature": 82.0, "foo": 12.3, "bar": -1202.23}, Tags: map[string]string{"location": "us-midwest", "source": "test-source"}, Timestamp: time.Unix(0, int64(1465839830100400201))},
 	}
 
@@ -36,7 +40,7 @@ func TestEscaping1(t *testing.T) {
 	}
 
 	currentTime := time.Now()
-	actual, _ := Parse(strings.Join(lines, "\n"), currentTime)
+	actual, _ := Parse([]byte(strings.Join(lines, "\n")), currentTime, time.Nanosecond)
 
 	expected := []general.Point{
 		{Measurement: "weat,he r", Fields: map[string]interface{}{"temperature": 82.0, "temperature_string": `hot, really "hot"!`}, Tags: map[string]string{`loc"ation, `: `us mid"west`}, Timestamp: time.Unix(0, int64(1465839830100400200))},
@@ -53,11 +57,11 @@ func TestEscaping2(t *testing.T) {
 	}
 
 	currentTime := time.Now()
-	actual, _ := Parse(strings.Join(lines, "\n"), currentTime)
+	actual, _ := Parse([]byte(strings.Join(lines, "\n")), currentTime, time.Nanosecond)
 
 	expected := []general.Point{
 		{Measurement: "weat=her", Fields: map[string]interface{}{`temperature_string`: `temp: hot`}, Tags: map[string]string{`location`: `us-midwest`}, Timestamp: time.Unix(0, int64(1465839830100400200))},
-		{Measurement: "weat=her", Fields: map[string]interface{}{`temp=erature_string`: `temp=hot`}, Tags: map[string]string{`loc=ation`: `us-mi=dwest`}, Timestamp: time.Unix(0, int64(1465839830100400201))},
+		{Measurement: "weat=her", Fields: map[string]interface{}{`temp=erature_string`: `temp\=hot`}, Tags: map[string]string{`loc=ation`: `us-mi=dwest`}, Timestamp: time.Unix(0, int64(1465839830100400201))},
 	}
 
 	assert.Equal(t, expected, actual, "The two objects should be the same.")
@@ -69,7 +73,7 @@ func TestIncorrectString(t *testing.T) {
 	}
 
 	currentTime := time.Now()
-	_, err := Parse(strings.Join(lines, "\n"), currentTime)
+	_, err := Parse([]byte(strings.Join(lines, "\n")), currentTime, time.Nanosecond)
 
 	// error should not be nil here
 	if err == nil {
@@ -85,7 +89,7 @@ func TestIntValue(t *testing.T) {
 	}
 
 	currentTime := time.Now()
-	actual, _ := Parse(strings.Join(lines, "\n"), currentTime)
+	actual, _ := Parse([]byte(strings.Join(lines, "\n")), currentTime, time.Nanosecond)
 
 	expected := []general.Point{
 		{Measurement: "value", Fields: map[string]interface{}{`value`: int64(0)}, Tags: map[string]string{`label`: `state`, "customer": `stark`, `host`: `xyz.com`, `service`: `test-service`}, Timestamp: time.Unix(0, int64(1613985840702344400))},
@@ -94,6 +98,216 @@ func TestIntValue(t *testing.T) {
 	assert.Equal(t, expected, actual, "The two objects should be the same.")
 }
 
+func TestQuotedStrings(t *testing.T) {
+	lines := []string{
+		`weather,location=us-midwest description="a, b c=d",other="with\nbackslash" 1465839830100400200`,
+		"weather,location=us-midwest description=\"multi\nline\" 1465839830100400200",
+		`weather,location=us-midwest description="" 1465839830100400200`,
+		`weather,location=us-midwest description="a\,b \"c\" \\d" 1465839830100400200`, // only quotes and backslashes are escaped in strings
+	}
+
+	currentTime := time.Now()
+	actual, err := Parse([]byte(strings.Join(lines, "\n")), currentTime, time.Nanosecond)
+	assert.Nil(t, err)
+
+	expected := []general.Point{
+		{Measurement: "weather", Fields: map[string]interface{}{"description": "a, b c=d", "other": `with\nbackslash`}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weather", Fields: map[string]interface{}{"description": "multi\nline"}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weather", Fields: map[string]interface{}{"description": ""}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weather", Fields: map[string]interface{}{"description": `a\,b "c" \d`}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+	}
+
+	assert.Equal(t, expected, actual, "The two objects should be the same.")
+}
+
+func TestValueTypes(t *testing.T) {
+	lines := []string{
+		"values a=1,b=-1.5,c=1e3,d=-2.5E-2,e=12i,f=-12i,g=12u,l=18446744073709551615u,h=true,i=F,j=FALSE,k=t 1465839830100400200",
+	}
+
+	currentTime := time.Now()
+	actual, err := Parse([]byte(strings.Join(lines, "\n")), currentTime, time.Nanosecond)
+	assert.Nil(t, err)
+
+	expected := []general.Point{
+		{Measurement: "values", Fields: map[string]interface{}{
+			"a": 1.0, "b": -1.5, "c": 1000.0, "d": -0.025,
+			"e": int64(12), "f": int64(-12), "g": uint64(12), "l": uint64(18446744073709551615),
+			"h": true, "i": false, "j": false, "k": true,
+		}, Tags: map[string]string{}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+	}
+
+	assert.Equal(t, expected, actual, "The two objects should be the same.")
+}
+
+func TestWhitespaceAndLineEndings(t *testing.T) {
+	input := "  weather,location=us-midwest  temperature=82   1465839830100400200  \r\n\r\n\tweather temperature=83\r\n"
+
+	currentTime := time.Now()
+	actual, err := Parse([]byte(input), currentTime, time.Nanosecond)
+	assert.Nil(t, err)
+
+	expected := []general.Point{
+		{Measurement: "weather", Fields: map[string]interface{}{"temperature": 82.0}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weather", Fields: map[string]interface{}{"temperature": 83.0}, Tags: map[string]string{}, Timestamp: currentTime},
+	}
+
+	assert.Equal(t, expected, actual, "The two objects should be the same.")
+}
+
+func TestInvalidLines(t *testing.T) {
+	invalidLines := []string{
+		"weather",                                      // no fields
+		"weather,location=us-midwest",                  // no fields
+		"weather,location temperature=82",              // tag without value
+		"weather,=us-midwest temperature=82",           // tag without key
+		",location=us-midwest temperature=82",          // no measurement
+		"weather temperature=",                         // field without value
+		"weather temperature",                          // field without value
+		"weather =82",                                  // field without key
+		"weather temperature=82,",                      // trailing comma
+		"weather temperature=hot",                      // unquoted string
+		`weather temperature="hot`,                     // unterminated string
+		`weather temperature="hot"x`,                   // garbage after string
+		"weather temperature=82x",                      // invalid number
+		"weather temperature=NaN",                      // NaN is not allowed
+		"weather temperature=12.5i",                    // invalid integer
+		"weather temperature=-12u",                     // invalid unsigned integer
+		"weather temperature=18446744073709551616u",    // unsigned integer out of range
+		"weather temperature=82 abc",                   // invalid timestamp
+		"weather temperature=82 1465839830100400200 1", // data after timestamp
+		"weather temperature=82 99999999999999999999",  // timestamp out of range
+	}
+
+	for _, line := range invalidLines {
+		_, err := Parse([]byte(line), time.Now(), time.Nanosecond)
+		assert.NotNil(t, err, "Expected error for line: %s", line)
+	}
+
+	// timestamps that overflow after applying the precision are invalid too
+	_, err := Parse([]byte("weather temperature=82 1465839830100400200"), time.Now(), time.Second)
+	assert.NotNil(t, err)
+}
+
+func TestPartialParse(t *testing.T) {
+	lines := []string{
+		"# comment",
+		"weather,location=us-midwest temperature=82 1465839830100400200",
+		"weather,location=us-midwest temperature=hot 1465839830100400200",
+		"weather,location=us-midwest description=\"multi\nline\" 1465839830100400200",
+		"weather,location=us-midwest",
+		"weather,location=us-midwest temperature=83 1465839830100400201",
+	}
+
+	actual, err := Parse([]byte(strings.Join(lines, "\n")), time.Now(), time.Nanosecond)
+
+	expected := []general.Point{
+		{Measurement: "weather", Fields: map[string]interface{}{"temperature": 82.0}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weather", Fields: map[string]interface{}{"description": "multi\nline"}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weather", Fields: map[string]interface{}{"temperature": 83.0}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: time.Unix(0, int64(1465839830100400201))},
+	}
+	assert.Equal(t, expected, actual, "The two objects should be the same.")
+
+	parseErrors, ok := err.(ParseErrors)
+	assert.True(t, ok)
+	assert.Len(t, parseErrors, 2)
+	assert.Equal(t, 3, parseErrors[0].Line)
+	assert.Equal(t, 6, parseErrors[1].Line)
+}
+
+func TestParseStream(t *testing.T) {
+	lines := []string{
+		"# comment",
+		"weather,location=us-midwest temperature=82 1465839830100400200",
+		"weather,location=us-midwest temperature=hot 1465839830100400200",
+		"weather,location=us-midwest description=\"multi\nline\" 1465839830100400200",
+		"weather,location=us-midwest",
+		"weather,location=us-midwest temperature=83 1465839830100400201",
+		"weather,location=us-midwest temperature=84",
+	}
+	input := []byte(strings.Join(lines, "\n"))
+
+	currentTime := time.Now()
+	expected, expectedErr := Parse(input, currentTime, time.Nanosecond)
+
+	// the result must not depend on how the input is split into reads or batches
+	for _, batchSize := range []int{1, 2, 100} {
+		for _, r := range []io.Reader{bytes.NewReader(input), iotest.OneByteReader(bytes.NewReader(input))} {
+			var actual []general.Point
+			var batches int
+			handle := func(points []general.Point) error {
+				assert.LessOrEqual(t, len(points), batchSize)
+				actual = append(actual, points...)
+				batches++
+				return nil
+			}
+
+			err := ParseStream(r, currentTime, time.Nanosecond, batchSize, handle)
+			assert.Equal(t, expected, actual)
+			assert.Equal(t, expectedErr, err)
+			assert.Equal(t, (len(expected)+batchSize-1)/batchSize, batches)
+		}
+	}
+}
+
+func TestParseStreamErrors(t *testing.T) {
+	input := []byte("weather temperature=82\nweather temperature=83\n")
+
+	// errors of the handler stop parsing
+	handlerErr := errors.New("output failed")
+	calls := 0
+	err := ParseStream(bytes.NewReader(input), time.Now(), time.Nanosecond, 1, func(points []general.Point) error {
+		calls++
+		return handlerErr
+	})
+	assert.Equal(t, handlerErr, err)
+	assert.Equal(t, 1, calls)
+
+	// read errors are returned
+	err = ParseStream(iotest.TimeoutReader(bytes.NewReader(input)), time.Now(), time.Nanosecond, 10, func(points []general.Point) error { return nil })
+	assert.Equal(t, iotest.ErrTimeout, err)
+
+	// lines are limited in length
+	long := append([]byte("weather description=\""), bytes.Repeat([]byte("a"), maxStreamLineSize+streamChunkSize)...)
+	err = ParseStream(bytes.NewReader(long), time.Now(), time.Nanosecond, 10, func(points []general.Point) error { return nil })
+	assert.NotNil(t, err)
+}
+
+func TestParsePoint(t *testing.T) {
+	currentTime := time.Now()
+	actual, err := ParsePoint([]byte("weather,location=us-midwest temperature=82i"), currentTime, time.Nanosecond)
+	assert.Nil(t, err)
+	assert.Equal(t, general.Point{Measurement: "weather", Fields: map[string]interface{}{"temperature": int64(82)}, Tags: map[string]string{"location": "us-midwest"}, Timestamp: currentTime}, actual)
+
+	_, err = ParsePoint([]byte("weather temperature=82\nweather temperature=83"), currentTime, time.Nanosecond)
+	assert.NotNil(t, err)
+}
+
+func TestPrecision(t *testing.T) {
+	lines := []string{
+		"weather,location=us-midwest temperature=82 1465839830",
+	}
+
+	currentTime := time.Now()
+
+	precision, err := ParsePrecision("s")
+	assert.Nil(t, err)
+	actual, _ := Parse([]byte(strings.Join(lines, "\n")), currentTime, precision)
+	assert.Equal(t, time.Unix(1465839830, 0), actual[0].Timestamp)
+
+	precision, err = ParsePrecision("ms")
+	assert.Nil(t, err)
+	actual, _ = Parse([]byte(strings.Join(lines, "\n")), currentTime, precision)
+	assert.Equal(t, time.Unix(1465839, 830000000), actual[0].Timestamp)
+
+	precision, err = ParsePrecision("")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Nanosecond, precision)
+
+	_, err = ParsePrecision("foo")
+	assert.NotNil(t, err)
+}
+
 func BenchmarkBasicFunctionality(b *testing.B) {
 	potentialLines := []string{
 		"weather,location=us-midwest temperature=82 1465839830100400200", // basic line
@@ -112,5 +326,5 @@ func BenchmarkBasicFunctionality(b *testing.B) {
 
 	b.ResetTimer()
 
-	_, _ = Parse(str, currentTime)
+	_, _ = Parse([]byte(str), currentTime, time.Nanosecond)
 }
@@ -0,0 +1,168 @@
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// Serialize encodes points as line protocol with nanosecond timestamps, one line per point.
+// Tags and fields are written sorted by key and every point that Parse produces is guaranteed to be parsed back unchanged
+func Serialize(points []general.Point) ([]byte, error) {
+	var buf []byte
+	for i, point := range points {
+		var err error
+		buf, err = AppendPoint(buf, point)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to serialize point %d: %w", i, err)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf, nil
+}
+
+// AppendPoint appends a single point as line protocol (without trailing newline) to buf.
+// Tags with empty values are skipped and points with zero timestamp are written without timestamp
+func AppendPoint(buf []byte, point general.Point) ([]byte, error) {
+	if point.Measurement == "" {
+		return buf, errors.New("missing measurement")
+	}
+	if point.Measurement[0] == '#' || point.Measurement[0] == '\t' {
+		return buf, errors.New("measurement must not start with '#' or a tab")
+	}
+	if len(point.Fields) == 0 {
+		return buf, errors.New("missing fields")
+	}
+
+	buf, err := appendEscaped(buf, point.Measurement, isMeasurementEnd)
+	if err != nil {
+		return buf, err
+	}
+
+	tagKeys := make([]string, 0, len(point.Tags))
+	for k := range point.Tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		v := point.Tags[k]
+		if v == "" {
+			continue
+		}
+		if k == "" {
+			return buf, errors.New("missing tag key")
+		}
+		buf = append(buf, ',')
+		if buf, err = appendEscaped(buf, k, isTagKeyEnd); err != nil {
+			return buf, err
+		}
+		buf = append(buf, '=')
+		if buf, err = appendEscaped(buf, v, isTagValueEnd); err != nil {
+			return buf, err
+		}
+	}
+
+	fieldKeys := make([]string, 0, len(point.Fields))
+	for k := range point.Fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for i, k := range fieldKeys {
+		if k == "" {
+			return buf, errors.New("missing field key")
+		}
+		if i == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		if buf, err = appendEscaped(buf, k, isFieldKeyEnd); err != nil {
+			return buf, err
+		}
+		buf = append(buf, '=')
+		if buf, err = appendFieldValue(buf, point.Fields[k]); err != nil {
+			return buf, fmt.Errorf("invalid field \"%s\": %w", k, err)
+		}
+	}
+
+	if !point.Timestamp.IsZero() {
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, point.Timestamp.UnixNano(), 10)
+	}
+
+	return buf, nil
+}
+
+// appendEscaped escapes all characters that would end the identifier and backslashes that would otherwise be read as escape character
+func appendEscaped(buf []byte, s string, isEnd func(c byte) bool) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\n':
+			return buf, fmt.Errorf("\"%s\" contains a newline", s)
+		case c == '\\':
+			if i+1 == len(s) || isEscapable(s[i+1]) {
+				buf = append(buf, '\\')
+			}
+		case isEnd(c):
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return buf, nil
+}
+
+func appendFieldValue(buf []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case float64:
+		return appendFloat(buf, v)
+	case float32:
+		return appendFloat(buf, float64(v))
+	case int64:
+		return append(strconv.AppendInt(buf, v, 10), 'i'), nil
+	case int:
+		return append(strconv.AppendInt(buf, int64(v), 10), 'i'), nil
+	case int32:
+		return append(strconv.AppendInt(buf, int64(v), 10), 'i'), nil
+	case uint64:
+		return append(strconv.AppendUint(buf, v, 10), 'u'), nil
+	case uint:
+		return append(strconv.AppendUint(buf, uint64(v), 10), 'u'), nil
+	case uint32:
+		return append(strconv.AppendUint(buf, uint64(v), 10), 'u'), nil
+	case bool:
+		return strconv.AppendBool(buf, v), nil
+	case string:
+		return appendString(buf, v), nil
+	default:
+		return buf, fmt.Errorf("unsupported field type %T", value)
+	}
+}
+
+func appendFloat(buf []byte, v float64) ([]byte, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return buf, fmt.Errorf("%v can not be represented", v)
+	}
+	return strconv.AppendFloat(buf, v, 'g', -1, 64), nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	if !strings.ContainsAny(s, `\"`) {
+		buf = append(buf, s...)
+	} else {
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if c == '"' || (c == '\\' && (i+1 == len(s) || isStringEscapable(s[i+1]))) {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,112 @@
+package influx
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerialize(t *testing.T) {
+	points := []general.Point{
+		{Measurement: "weather", Fields: map[string]interface{}{"temperature": 82.0, "count": int64(-3), "total": uint64(18446744073709551615), "up": true}, Tags: map[string]string{"location": "us-midwest", "source": "test"}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weat,he r", Fields: map[string]interface{}{"temperature_string": `hot, really "hot"!`}, Tags: map[string]string{`loc"ation, `: `us mid"west`, "empty": ""}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: `back\slash`, Fields: map[string]interface{}{`f\`: `C:\dir\`}, Tags: map[string]string{`t\,`: `v\ `}, Timestamp: time.Unix(0, int64(1465839830100400200))},
+		{Measurement: "weather", Fields: map[string]interface{}{"temperature": 1e21}, Tags: map[string]string{}},
+		{Measurement: "weather", Fields: map[string]interface{}{"description": `a\,b\"`}, Tags: map[string]string{}},
+	}
+
+	actual, err := Serialize(points)
+	assert.Nil(t, err)
+
+	expected := "weather,location=us-midwest,source=test count=-3i,temperature=82,total=18446744073709551615u,up=true 1465839830100400200\n" +
+		"weat\\,he\\ r,loc\"ation\\,\\ =us\\ mid\"west temperature_string=\"hot, really \\\"hot\\\"!\" 1465839830100400200\n" +
+		"back\\slash,t\\\\\\,=v\\\\\\  f\\\\=\"C:\\dir\\\\\" 1465839830100400200\n" +
+		"weather temperature=1e+21\n" +
+		"weather description=\"a\\,b\\\\\\\"\"\n"
+	assert.Equal(t, expected, string(actual))
+}
+
+func TestSerializeInvalidPoints(t *testing.T) {
+	invalidPoints := []general.Point{
+		{Measurement: "", Fields: map[string]interface{}{"value": 1.0}},
+		{Measurement: "#comment", Fields: map[string]interface{}{"value": 1.0}},
+		{Measurement: "weather", Fields: map[string]interface{}{}},
+		{Measurement: "weather", Fields: map[string]interface{}{"value": math.NaN()}},
+		{Measurement: "weather", Fields: map[string]interface{}{"value": math.Inf(1)}},
+		{Measurement: "weather", Fields: map[string]interface{}{"value": []string{"a"}}},
+		{Measurement: "weather", Fields: map[string]interface{}{"": 1.0}},
+		{Measurement: "weather", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"": "value"}},
+		{Measurement: "weather", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "multi\nline"}},
+		{Measurement: "multi\nline", Fields: map[string]interface{}{"value": 1.0}},
+	}
+
+	for _, point := range invalidPoints {
+		_, err := Serialize([]general.Point{point})
+		assert.NotNil(t, err, "Expected error for point: %v", point)
+	}
+}
+
+// randomString returns a string that is likely to contain characters with special meaning in line protocol
+func randomString(r *rand.Rand, allowNewline bool) string {
+	alphabet := []string{"a", "b", "Z", "0", "9", ",", " ", "=", "\"", "\\", "#", "\t", "ä", "\r", "i", "u", "-", "."}
+	if allowNewline {
+		alphabet = append(alphabet, "\n")
+	}
+	n := 1 + r.Intn(8)
+	s := ""
+	for i := 0; i < n; i++ {
+		s += alphabet[r.Intn(len(alphabet))]
+	}
+	return s
+}
+
+func randomPoint(r *rand.Rand) general.Point {
+	measurement := randomString(r, false)
+	for measurement[0] == '#' || measurement[0] == '\t' {
+		measurement = randomString(r, false)
+	}
+
+	tags := make(map[string]string)
+	for i := r.Intn(4); i > 0; i-- {
+		tags[randomString(r, false)] = randomString(r, false)
+	}
+
+	fields := make(map[string]interface{})
+	for i := 1 + r.Intn(5); i > 0; i-- {
+		key := randomString(r, false)
+		switch r.Intn(5) {
+		case 0:
+			fields[key] = (r.Float64() - 0.5) * math.Pow(10, float64(r.Intn(40)-20))
+		case 1:
+			fields[key] = r.Int63() - r.Int63()
+		case 2:
+			fields[key] = r.Uint64()
+		case 3:
+			fields[key] = r.Intn(2) == 0
+		default:
+			fields[key] = randomString(r, true)
+		}
+	}
+
+	return general.Point{Measurement: measurement, Fields: fields, Tags: tags, Timestamp: time.Unix(0, r.Int63()-r.Int63())}
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+
+	points := make([]general.Point, 1000)
+	for i := range points {
+		points[i] = randomPoint(r)
+	}
+
+	serialized, err := Serialize(points)
+	assert.Nil(t, err)
+
+	parsed, err := Parse(serialized, time.Now(), time.Nanosecond)
+	assert.Nil(t, err)
+	assert.Equal(t, points, parsed)
+}
@@ -0,0 +1,152 @@
+package influx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// QueryResponse is the response of the influxDB v1 /query endpoint
+type QueryResponse struct {
+	Results []QueryResult `json:"results"`
+}
+
+// QueryResult is the result of a single statement of a query
+type QueryResult struct {
+	StatementID int           `json:"statement_id"`
+	Series      []QuerySeries `json:"series,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+type QuerySeries struct {
+	Name    string          `json:"name"`
+	Columns []string        `json:"columns"`
+	Values  [][]interface{} `json:"values,omitempty"`
+}
+
+// Databases are the database names returned by SHOW DATABASES, the databases configured for the outputs. Databases created
+// by clients or written to are not recorded, so that clients can't grow the list without bound.
+type Databases struct {
+	names []string
+}
+
+// NewDatabases creates the list of the distinct non-empty names
+func NewDatabases(names ...string) *Databases {
+	unique := make(map[string]struct{}, len(names))
+	ret := &Databases{names: []string{}}
+	for _, name := range names {
+		if _, ok := unique[name]; ok || name == "" {
+			continue
+		}
+		unique[name] = struct{}{}
+		ret.names = append(ret.names, name)
+	}
+	sort.Strings(ret.names)
+	return ret
+}
+
+// List returns the sorted database names
+func (d *Databases) List() []string {
+	return d.names
+}
+
+// ExecuteMetaQuery answers the meta queries that clients like grafana, telegraf and the influx CLI send on startup
+// (SHOW DATABASES, CREATE DATABASE, SHOW RETENTION POLICIES, ...); the receiver stores no data, so SHOW MEASUREMENTS and
+// similar statements return empty results and all other statements (e.g. SELECT) result in a statement error
+func ExecuteMetaQuery(query string, databases *Databases) QueryResponse {
+	ret := QueryResponse{Results: []QueryResult{}}
+
+	for statementID, tokens := range splitStatements(query) {
+		result := executeStatement(tokens, databases)
+		result.StatementID = statementID
+		ret.Results = append(ret.Results, result)
+	}
+
+	return ret
+}
+
+// splitStatements splits a query into its statements and the statements into whitespace separated tokens; semicolons and
+// whitespace inside quoted strings and identifiers (e.g. "my;db" or 'a b') do not split, quotes can be escaped with "\"
+func splitStatements(query string) [][]string {
+	var statements [][]string
+	var tokens []string
+	var token strings.Builder
+	var quote rune
+	escaped := false
+
+	endToken := func() {
+		if token.Len() > 0 {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+	}
+	endStatement := func() {
+		endToken()
+		if len(tokens) > 0 {
+			statements = append(statements, tokens)
+			tokens = nil
+		}
+	}
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			token.WriteRune(r)
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			token.WriteRune(r)
+		case r == ';':
+			endStatement()
+		case unicode.IsSpace(r):
+			endToken()
+		default:
+			token.WriteRune(r)
+		}
+	}
+	endStatement()
+
+	return statements
+}
+
+func executeStatement(tokens []string, databases *Databases) QueryResult {
+	keyword := func(i int) string {
+		if i >= len(tokens) {
+			return ""
+		}
+		return strings.ToUpper(tokens[i])
+	}
+
+	switch {
+	case keyword(0) == "SHOW" && keyword(1) == "DATABASES":
+		values := [][]interface{}{}
+		for _, name := range databases.List() {
+			values = append(values, []interface{}{name})
+		}
+		return QueryResult{Series: []QuerySeries{{Name: "databases", Columns: []string{"name"}, Values: values}}}
+
+	case keyword(0) == "CREATE" && keyword(1) == "DATABASE" && len(tokens) >= 3:
+		// clients like telegraf create their database on startup, the receiver accepts it without recording it
+		return QueryResult{}
+
+	case keyword(0) == "SHOW" && keyword(1) == "RETENTION" && keyword(2) == "POLICIES":
+		return QueryResult{Series: []QuerySeries{{
+			Columns: []string{"name", "duration", "shardGroupDuration", "replicaN", "default"},
+			Values:  [][]interface{}{{"autogen", "0s", "168h0m0s", 1, true}},
+		}}}
+
+	case keyword(0) == "SHOW" && (keyword(1) == "MEASUREMENTS" || keyword(1) == "SERIES" || keyword(1) == "USERS" ||
+		(keyword(1) == "TAG" && (keyword(2) == "KEYS" || keyword(2) == "VALUES")) || (keyword(1) == "FIELD" && keyword(2) == "KEYS")):
+		return QueryResult{}
+
+	default:
+		return QueryResult{Error: fmt.Sprintf("statement not supported by metrics-receiver: %s", strings.Join(tokens, " "))}
+	}
+}
@@ -0,0 +1,37 @@
+package influx
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteMetaQuery(t *testing.T) {
+	databases := NewDatabases("telegraf", "", "metrics", "telegraf")
+
+	// created databases are not recorded
+	response := ExecuteMetaQuery(`CREATE DATABASE "my-db" WITH DURATION 30d; show databases;`, databases)
+	actual, err := json.Marshal(response)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"results": [
+		{"statement_id": 0},
+		{"statement_id": 1, "series": [{"name": "databases", "columns": ["name"], "values": [["metrics"], ["telegraf"]]}]}
+	]}`, string(actual))
+
+	response = ExecuteMetaQuery(`SHOW RETENTION POLICIES ON "tele;graf"; SHOW TAG KEYS FROM cpu; SELECT * FROM cpu WHERE host = 'a;  \'b'`, databases)
+	actual, err = json.Marshal(response)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"results": [
+		{"statement_id": 0, "series": [{"name": "", "columns": ["name", "duration", "shardGroupDuration", "replicaN", "default"], "values": [["autogen", "0s", "168h0m0s", 1, true]]}]},
+		{"statement_id": 1},
+		{"statement_id": 2, "error": "statement not supported by metrics-receiver: SELECT * FROM cpu WHERE host = 'a;  \\'b'"}
+	]}`, string(actual))
+}
+
+func TestExecuteMetaQueryEmpty(t *testing.T) {
+	response := ExecuteMetaQuery("SHOW DATABASES", NewDatabases())
+	actual, err := json.Marshal(response)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"results": [{"statement_id": 0, "series": [{"name": "databases", "columns": ["name"]}]}]}`, string(actual))
+}
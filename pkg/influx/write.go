@@ -3,6 +3,7 @@ package influx
 import (
 	"context"
 	"fmt"
+	"math"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go"
 	influxdb1 "github.com/influxdata/influxdb1-client/v2"
@@ -64,7 +65,7 @@ func insertRowsInfluxV1(writePoints []general.Point, config *config.OutputInflux
 		return err
 	}
 	for _, p := range writePoints {
-		point, err := influxdb1.NewPoint(p.Measurement, p.Tags, p.Fields, p.Timestamp)
+		point, err := influxdb1.NewPoint(p.Measurement, p.Tags, convertFieldsInfluxV1(p.Fields), p.Timestamp)
 		if err != nil {
 			return err
 		}
@@ -79,6 +80,32 @@ func insertRowsInfluxV1(writePoints []general.Point, config *config.OutputInflux
 	return nil
 }
 
+// convertFieldsInfluxV1 converts unsigned integer fields, because influxDB v1 does not support them:
+// they become signed integers if they fit, otherwise floats (losing precision)
+func convertFieldsInfluxV1(fields map[string]interface{}) map[string]interface{} {
+	var converted map[string]interface{}
+	for k, v := range fields {
+		if u, ok := v.(uint64); ok {
+			if converted == nil {
+				converted = make(map[string]interface{}, len(fields))
+				for k2, v2 := range fields {
+					converted[k2] = v2
+				}
+			}
+			if u <= math.MaxInt64 {
+				converted[k] = int64(u)
+			} else {
+				converted[k] = float64(u)
+			}
+		}
+	}
+
+	if converted == nil {
+		return fields
+	}
+	return converted
+}
+
 func insertRowsInfluxV2(writePoints []general.Point, config *config.OutputInflux) error {
 
 	// create new client with default option for server url authenticate by token
@@ -88,6 +115,7 @@ func insertRowsInfluxV2(writePoints []general.Point, config *config.OutputInflux
 	// user blocking write client for writes to desired bucket
 	writeAPI := client.WriteAPIBlocking(config.Org, config.DbName)
 
+	// booleans and unsigned integers are supported natively by influxDB v2, so fields are passed through unchanged
 	for _, p := range writePoints {
 		p1 := influxdb2.NewPoint(p.Measurement,
 			p.Tags,
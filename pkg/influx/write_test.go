@@ -98,3 +98,15 @@ func TestBuildPointsInflux(t *testing.T) {
 	}
 	assert.Equal(t, expected, rows)
 }
+
+func TestConvertFieldsInfluxV1(t *testing.T) {
+	fields := map[string]interface{}{"small": uint64(12), "large": uint64(18446744073709551615), "bool": true, "int": int64(-1)}
+
+	converted := convertFieldsInfluxV1(fields)
+
+	expected := map[string]interface{}{"small": int64(12), "large": float64(18446744073709551615), "bool": true, "int": int64(-1)}
+	assert.Equal(t, expected, converted)
+
+	// the original fields must not be modified
+	assert.Equal(t, uint64(12), fields["small"])
+}
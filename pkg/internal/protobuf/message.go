@@ -0,0 +1,25 @@
+package protobuf
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// ConsumeMessage iterates over all fields of a protobuf message; the callback consumes the field value and returns the number
+// of bytes it consumed (negative values are protowire error codes)
+func ConsumeMessage(b []byte, consumeField func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := consumeField(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package jsonformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// point is the JSON representation of a general.Point
+type point struct {
+	Measurement string                 `json:"measurement"`
+	Tags        map[string]string      `json:"tags"`
+	Fields      map[string]interface{} `json:"fields"`
+	Timestamp   interface{}            `json:"timestamp"`
+}
+
+// PointError describes a point of a JSON payload that failed validation; Index is the position of the point in the array
+type PointError struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+func (e PointError) Error() string {
+	return fmt.Sprintf("point %d: %s", e.Index, e.Reason)
+}
+
+// ValidationErrors is returned by Parse if one or more points failed validation
+type ValidationErrors []PointError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d points failed validation, first error: %s", len(e), e[0].Error())
+}
+
+// Parse parses a JSON array of points of the form {"measurement": "...", "tags": {...}, "fields": {...}, "timestamp": ...}.
+// The timestamp is either an RFC3339 string or an integer epoch in units of precision; points without timestamp get currentTimestamp.
+// Integral field values (without fraction or exponent) become int64 (or uint64 if they don't fit), other numbers float64.
+// The payload is only accepted as a whole: if any point is invalid, a ValidationErrors error listing all invalid points is returned
+func Parse(input []byte, currentTimestamp time.Time, precision time.Duration) ([]general.Point, error) {
+	var rawPoints []json.RawMessage
+	if err := json.Unmarshal(input, &rawPoints); err != nil {
+		return nil, fmt.Errorf("Expected a JSON array of points: %w", err)
+	}
+
+	ret := make([]general.Point, 0, len(rawPoints))
+	var validationErrors ValidationErrors
+	for i, rawPoint := range rawPoints {
+		p, err := parsePoint(rawPoint, currentTimestamp, precision)
+		if err != nil {
+			validationErrors = append(validationErrors, PointError{Index: i, Reason: err.Error()})
+			continue
+		}
+		ret = append(ret, p)
+	}
+
+	if len(validationErrors) > 0 {
+		return nil, validationErrors
+	}
+	return ret, nil
+}
+
+func parsePoint(rawPoint json.RawMessage, currentTimestamp time.Time, precision time.Duration) (general.Point, error) {
+	decoder := json.NewDecoder(bytes.NewReader(rawPoint))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+
+	var p point
+	if err := decoder.Decode(&p); err != nil {
+		return general.Point{}, err
+	}
+
+	if p.Measurement == "" {
+		return general.Point{}, errors.New("missing measurement")
+	}
+
+	tags := make(map[string]string, len(p.Tags))
+	for k, v := range p.Tags {
+		if k == "" {
+			return general.Point{}, errors.New("empty tag key")
+		}
+		tags[k] = v
+	}
+
+	if len(p.Fields) == 0 {
+		return general.Point{}, errors.New("missing fields")
+	}
+	fields := make(map[string]interface{}, len(p.Fields))
+	for k, v := range p.Fields {
+		if k == "" {
+			return general.Point{}, errors.New("empty field key")
+		}
+		value, err := convertFieldValue(v)
+		if err != nil {
+			return general.Point{}, fmt.Errorf("field \"%s\": %w", k, err)
+		}
+		fields[k] = value
+	}
+
+	timestamp, err := convertTimestamp(p.Timestamp, currentTimestamp, precision)
+	if err != nil {
+		return general.Point{}, err
+	}
+
+	return general.Point{Measurement: p.Measurement, Fields: fields, Tags: tags, Timestamp: timestamp}, nil
+}
+
+func convertFieldValue(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case string, bool:
+		return value, nil
+	case json.Number:
+		s := value.String()
+		if !strings.ContainsAny(s, ".eE") {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			}
+			if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+				return u, nil
+			}
+			return nil, fmt.Errorf("integer %s out of range", s)
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("number %s out of range", s)
+		}
+		return f, nil
+	case nil:
+		return nil, errors.New("value must not be null")
+	default:
+		return nil, errors.New("value must be a number, string or boolean")
+	}
+}
+
+func convertTimestamp(v interface{}, currentTimestamp time.Time, precision time.Duration) (time.Time, error) {
+	switch value := v.(type) {
+	case nil:
+		return currentTimestamp, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid RFC3339 timestamp \"%s\"", value)
+		}
+		return t, nil
+	case json.Number:
+		t, err := strconv.ParseInt(value.String(), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch timestamp %s", value)
+		}
+		unit := int64(precision)
+		if t > math.MaxInt64/unit || t < math.MinInt64/unit {
+			return time.Time{}, fmt.Errorf("timestamp %s out of range", value)
+		}
+		return time.Unix(0, t*unit), nil
+	default:
+		return time.Time{}, errors.New("timestamp must be an RFC3339 string or an integer epoch")
+	}
+}
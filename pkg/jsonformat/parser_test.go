@@ -0,0 +1,63 @@
+package jsonformat
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	body := `[
+		{"measurement": "cpu", "tags": {"host": "host1"}, "fields": {"usage": 0.5, "cores": 8, "big": 18446744073709551615, "model": "x86", "online": true}, "timestamp": 1613985840},
+		{"measurement": "cpu", "fields": {"usage": 1e2}, "timestamp": "2021-02-22T09:24:00.5Z"},
+		{"measurement": "mem", "fields": {"free": 1024}}
+	]`
+
+	currentTime := time.Now()
+	actual, err := Parse([]byte(body), currentTime, time.Second)
+	assert.Nil(t, err)
+
+	expected := []general.Point{
+		{Measurement: "cpu", Fields: map[string]interface{}{"usage": 0.5, "cores": int64(8), "big": uint64(18446744073709551615), "model": "x86", "online": true}, Tags: map[string]string{"host": "host1"}, Timestamp: time.Unix(1613985840, 0)},
+		{Measurement: "cpu", Fields: map[string]interface{}{"usage": 100.0}, Tags: map[string]string{}, Timestamp: time.Date(2021, 2, 22, 9, 24, 0, 500000000, time.UTC)},
+		{Measurement: "mem", Fields: map[string]interface{}{"free": int64(1024)}, Tags: map[string]string{}, Timestamp: currentTime},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseInvalid(t *testing.T) {
+	body := `[
+		{"fields": {"a": 1}},
+		{"measurement": "m"},
+		{"measurement": "m", "fields": {"a": null}},
+		{"measurement": "m", "fields": {"a": [1]}},
+		{"measurement": "m", "fields": {"a": 1}, "tags": {"t": 1}},
+		{"measurement": "m", "fields": {"a": 1}, "timestamp": "yesterday"},
+		{"measurement": "m", "fields": {"a": 1}, "timestamp": 1.5},
+		{"measurement": "m", "fields": {"a": 1}, "timestamp": 9223372036854775807},
+		{"measurement": "m", "fields": {"a": 1}, "unknown": 1},
+		{"measurement": "m", "fields": {"a": 99999999999999999999}},
+		{"measurement": "m", "fields": {"a": 1}}
+	]`
+
+	actual, err := Parse([]byte(body), time.Now(), time.Second)
+	assert.Nil(t, actual)
+
+	var validationErrors ValidationErrors
+	assert.True(t, errors.As(err, &validationErrors))
+	assert.Len(t, validationErrors, 10)
+	for i, e := range validationErrors {
+		assert.Equal(t, i, e.Index)
+	}
+}
+
+func TestParseNoArray(t *testing.T) {
+	_, err := Parse([]byte(`{"measurement": "m", "fields": {"a": 1}}`), time.Now(), time.Second)
+	assert.NotNil(t, err)
+
+	var validationErrors ValidationErrors
+	assert.False(t, errors.As(err, &validationErrors))
+}
@@ -0,0 +1,73 @@
+package listener
+
+import (
+	"sync"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// maximum number of full batches waiting to be flushed before Add blocks
+const maxPendingBatches = 4
+
+// PointBuffer collects the points received by a listener and passes them to flush in batches, once batchSize points are
+// buffered or every flushInterval, so that the outputs are not written once per datagram. Flushing happens in a separate
+// goroutine, so a slow output does not block reading until maxPendingBatches batches are waiting.
+type PointBuffer struct {
+	batchSize int
+	flush     func(points []general.Point)
+
+	lock    sync.Mutex
+	points  []general.Point
+	pending chan []general.Point
+}
+
+// NewPointBuffer creates a buffer and starts flushing it
+func NewPointBuffer(batchSize int, flushInterval time.Duration, flush func(points []general.Point)) *PointBuffer {
+	b := &PointBuffer{
+		batchSize: batchSize,
+		flush:     flush,
+		pending:   make(chan []general.Point, maxPendingBatches),
+	}
+	go b.run(flushInterval)
+	return b
+}
+
+// Add buffers points; a full batch is handed over to the flushing goroutine
+func (b *PointBuffer) Add(points []general.Point) {
+	b.lock.Lock()
+	b.points = append(b.points, points...)
+	var batch []general.Point
+	if len(b.points) >= b.batchSize {
+		batch = b.points
+		b.points = nil
+	}
+	b.lock.Unlock()
+
+	if batch != nil {
+		b.pending <- batch
+	}
+}
+
+func (b *PointBuffer) take() []general.Point {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	points := b.points
+	b.points = nil
+	return points
+}
+
+func (b *PointBuffer) run(flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case batch := <-b.pending:
+			b.flush(batch)
+		case <-ticker.C:
+			if points := b.take(); len(points) > 0 {
+				b.flush(points)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package listener
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPointBuffer(t *testing.T) {
+	var lock sync.Mutex
+	var batches [][]general.Point
+	flush := func(points []general.Point) {
+		lock.Lock()
+		defer lock.Unlock()
+		batches = append(batches, points)
+	}
+	batchSizes := func() []int {
+		lock.Lock()
+		defer lock.Unlock()
+		var ret []int
+		for _, batch := range batches {
+			ret = append(ret, len(batch))
+		}
+		return ret
+	}
+
+	point := general.Point{Measurement: "cpu", Fields: map[string]interface{}{"value": 1.0}}
+	b := NewPointBuffer(3, 200*time.Millisecond, flush)
+
+	// a full batch is flushed right away
+	b.Add([]general.Point{point, point})
+	b.Add([]general.Point{point, point})
+	assert.Eventually(t, func() bool { return len(batchSizes()) == 1 }, 100*time.Millisecond, 5*time.Millisecond)
+	assert.Equal(t, []int{4}, batchSizes())
+
+	// the rest is flushed after the flush interval
+	b.Add([]general.Point{point})
+	assert.Eventually(t, func() bool { return len(batchSizes()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int{4, 1}, batchSizes())
+}
@@ -0,0 +1,144 @@
+package listener
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maximum number of bytes passed to the handler at once for stream connections
+const maxBatchSize = 1024 * 1024
+
+// MaxLineSize is the maximum length of a line received on a stream connection
+const MaxLineSize = 1024 * 1024
+
+// maximum size of a datagram
+const maxDatagramSize = 64 * 1024
+
+// Handler processes data received by a listener; for stream connections data always consists of complete lines
+type Handler func(data []byte)
+
+// ListenTCP accepts connections on address and passes the received newline separated lines to handle; lines that arrive
+// together are passed in one batch (up to maxBatchSize)
+func ListenTCP(address string, handle Handler, log *logrus.Logger) (net.Listener, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	go acceptConnections(l, handle, log)
+
+	return l, nil
+}
+
+// ListenUnix accepts connections on the unix domain socket at path and handles them like ListenTCP does; a stale socket file
+// left behind by a previous run is removed
+func ListenUnix(path string, handle Handler, log *logrus.Logger) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	go acceptConnections(l, handle, log)
+
+	return l, nil
+}
+
+func acceptConnections(l net.Listener, handle Handler, log *logrus.Logger) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if isTemporary(err) {
+				log.Warnf("Error accepting connection on %s: %v", l.Addr(), err)
+				continue
+			}
+			// the listener was closed
+			return
+		}
+		go serveConnection(conn, handle, log)
+	}
+}
+
+func serveConnection(conn net.Conn, handle Handler, log *logrus.Logger) {
+	defer conn.Close()
+
+	reader := bufio.NewReaderSize(conn, 64*1024)
+	var batch []byte
+	lineStart := 0
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		batch = append(batch, chunk...)
+
+		if err == bufio.ErrBufferFull {
+			// the line continues, the connection is closed if it grows too long, as it would have to be kept in memory
+			if len(batch)-lineStart > MaxLineSize {
+				log.Warnf("Closing connection %s: line exceeds %d bytes", conn.RemoteAddr(), MaxLineSize)
+				return
+			}
+			continue
+		}
+		lineStart = len(batch)
+
+		// flush when there is no more data waiting, so that lines are not held back until the connection is closed
+		if len(batch) > 0 && (err != nil || reader.Buffered() == 0 || len(batch) >= maxBatchSize) {
+			handle(batch)
+			batch = nil
+			lineStart = 0
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				log.Warnf("Error reading from connection %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// ListenUDP receives datagrams on address and passes each datagram to handle
+func ListenUDP(address string, handle Handler, log *logrus.Logger) (net.PacketConn, error) {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if isTemporary(err) {
+					log.Warnf("Error reading datagram on %s: %v", conn.LocalAddr(), err)
+					continue
+				}
+				// the connection was closed
+				return
+			}
+
+			// the buffer is reused for the next datagram, so the handler gets a copy
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			handle(data)
+		}
+	}()
+
+	return conn, nil
+}
+
+func isTemporary(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Temporary()
+}
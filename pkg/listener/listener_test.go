@@ -0,0 +1,131 @@
+package listener
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type receiver struct {
+	lock     sync.Mutex
+	received []byte
+}
+
+func (r *receiver) handle(data []byte) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.received = append(r.received, data...)
+}
+
+func (r *receiver) get() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return string(r.received)
+}
+
+func TestListenTCP(t *testing.T) {
+	r := &receiver{}
+	l, err := ListenTCP("127.0.0.1:0", r.handle, logrus.StandardLogger())
+	assert.Nil(t, err)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	_, err = conn.Write([]byte("line1\nline2\n"))
+	assert.Nil(t, err)
+	_, err = conn.Write([]byte("line3 without newline"))
+	assert.Nil(t, err)
+	conn.Close()
+
+	assert.Eventually(t, func() bool { return r.get() == "line1\nline2\nline3 without newline" }, time.Second, 10*time.Millisecond)
+}
+
+func TestListenUDP(t *testing.T) {
+	r := &receiver{}
+	l, err := ListenUDP("127.0.0.1:0", r.handle, logrus.StandardLogger())
+	assert.Nil(t, err)
+	defer l.Close()
+
+	conn, err := net.Dial("udp", l.LocalAddr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("line1\nline2\n"))
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool { return r.get() == "line1\nline2\n" }, time.Second, 10*time.Millisecond)
+}
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listener")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.sock")
+
+	// a stale socket file must not prevent listening
+	stale, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	r := &receiver{}
+	l, err := ListenUnix(path, r.handle, logrus.StandardLogger())
+	assert.Nil(t, err)
+	defer l.Close()
+
+	conn, err := net.Dial("unix", path)
+	assert.Nil(t, err)
+	_, err = conn.Write([]byte("line1\nline2\n"))
+	assert.Nil(t, err)
+	conn.Close()
+
+	assert.Eventually(t, func() bool { return r.get() == "line1\nline2\n" }, time.Second, 10*time.Millisecond)
+}
+
+func TestListenUnixNoSocket(t *testing.T) {
+	f, err := ioutil.TempFile("", "listener")
+	assert.Nil(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, err = ListenUnix(f.Name(), func(data []byte) {}, logrus.StandardLogger())
+	assert.NotNil(t, err)
+}
+
+func TestListenTCPLineTooLong(t *testing.T) {
+	r := &receiver{}
+	l, err := ListenTCP("127.0.0.1:0", r.handle, logrus.StandardLogger())
+	assert.Nil(t, err)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("line1\n"))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return r.get() == "line1\n" }, time.Second, 10*time.Millisecond)
+
+	// the connection is closed once the line exceeds the maximum length
+	long := make([]byte, 64*1024)
+	for i := range long {
+		long[i] = 'x'
+	}
+	closed := false
+	for i := 0; i < 2*1024*1024/len(long) && !closed; i++ {
+		_, err = conn.Write(long)
+		closed = err != nil
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	if assert.NotNil(t, err) {
+		netErr, ok := err.(net.Error)
+		assert.False(t, ok && netErr.Timeout(), "connection was not closed")
+	}
+	assert.Equal(t, "line1\n", r.get())
+}
@@ -0,0 +1,343 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// the types below mirror the messages of opentelemetry/proto/collector/metrics/v1/metrics_service.proto (and the messages it references);
+// the json tags follow the OTLP JSON encoding, the protobuf encoding is decoded into the same types (see protobuf.go)
+
+type exportMetricsServiceRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeMetrics struct {
+	Scope   instrumentationScope `json:"scope"`
+	Metrics []metric             `json:"metrics"`
+}
+
+type instrumentationScope struct {
+	Name       string     `json:"name"`
+	Version    string     `json:"version"`
+	Attributes []keyValue `json:"attributes"`
+}
+
+type metric struct {
+	Name      string     `json:"name"`
+	Gauge     *gauge     `json:"gauge"`
+	Sum       *sum       `json:"sum"`
+	Histogram *histogram `json:"histogram"`
+	Summary   *summary   `json:"summary"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type histogram struct {
+	DataPoints []histogramDataPoint `json:"dataPoints"`
+}
+
+type summary struct {
+	DataPoints []summaryDataPoint `json:"dataPoints"`
+}
+
+type numberDataPoint struct {
+	Attributes   []keyValue `json:"attributes"`
+	TimeUnixNano jsonUint64 `json:"timeUnixNano"`
+	AsDouble     *float64   `json:"asDouble"`
+	AsInt        *jsonInt64 `json:"asInt"`
+}
+
+type histogramDataPoint struct {
+	Attributes     []keyValue   `json:"attributes"`
+	TimeUnixNano   jsonUint64   `json:"timeUnixNano"`
+	Count          jsonUint64   `json:"count"`
+	Sum            *float64     `json:"sum"`
+	BucketCounts   []jsonUint64 `json:"bucketCounts"`
+	ExplicitBounds []float64    `json:"explicitBounds"`
+	Min            *float64     `json:"min"`
+	Max            *float64     `json:"max"`
+}
+
+type summaryDataPoint struct {
+	Attributes     []keyValue        `json:"attributes"`
+	TimeUnixNano   jsonUint64        `json:"timeUnixNano"`
+	Count          jsonUint64        `json:"count"`
+	Sum            float64           `json:"sum"`
+	QuantileValues []valueAtQuantile `json:"quantileValues"`
+}
+
+type valueAtQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string      `json:"stringValue"`
+	BoolValue   *bool        `json:"boolValue"`
+	IntValue    *jsonInt64   `json:"intValue"`
+	DoubleValue *float64     `json:"doubleValue"`
+	ArrayValue  *arrayValue  `json:"arrayValue"`
+	KvlistValue *kvlistValue `json:"kvlistValue"`
+	BytesValue  []byte       `json:"bytesValue"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+type kvlistValue struct {
+	Values []keyValue `json:"values"`
+}
+
+// the OTLP JSON encoding represents 64 bit integers as strings, but numbers are accepted as well
+
+type jsonUint64 uint64
+
+func (v *jsonUint64) UnmarshalJSON(b []byte) error {
+	s, err := unquoteJSONNumber(b)
+	if err != nil {
+		return err
+	}
+	parsed, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = jsonUint64(parsed)
+	return nil
+}
+
+type jsonInt64 int64
+
+func (v *jsonInt64) UnmarshalJSON(b []byte) error {
+	s, err := unquoteJSONNumber(b)
+	if err != nil {
+		return err
+	}
+	parsed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = jsonInt64(parsed)
+	return nil
+}
+
+func unquoteJSONNumber(b []byte) (string, error) {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		err := json.Unmarshal(b, &s)
+		return s, err
+	}
+	return string(b), nil
+}
+
+// ParseJSON decodes an OTLP/HTTP ExportMetricsServiceRequest in the JSON encoding and converts it into points
+func ParseJSON(body []byte, currentTimestamp time.Time) ([]general.Point, error) {
+	var req exportMetricsServiceRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("Failed to decode OTLP metrics request: %w", err)
+	}
+	return convertRequest(req, currentTimestamp)
+}
+
+// ParseProtobuf decodes an OTLP/HTTP ExportMetricsServiceRequest in the protobuf encoding and converts it into points
+func ParseProtobuf(body []byte, currentTimestamp time.Time) ([]general.Point, error) {
+	req, err := decodeExportMetricsServiceRequest(body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode OTLP metrics request: %w", err)
+	}
+	return convertRequest(req, currentTimestamp)
+}
+
+// convertRequest creates one point per data point: metric name -> measurement, resource, scope and data point attributes -> tags
+// (in this order, later attributes overwrite earlier ones with the same key). Gauges and sums are written to the field "value",
+// histograms to "count", "sum", "min", "max" and the cumulative bucket counts "le_<bound>" (like prometheus does), summaries
+// to "count", "sum" and "quantile_<quantile>". Exponential histograms are not supported and are skipped
+func convertRequest(req exportMetricsServiceRequest, currentTimestamp time.Time) ([]general.Point, error) {
+	var ret []general.Point
+
+	for _, rm := range req.ResourceMetrics {
+		resourceTags := make(map[string]string)
+		addAttributes(resourceTags, rm.Resource.Attributes)
+
+		for _, sm := range rm.ScopeMetrics {
+			scopeTags := general.CopyTags(resourceTags)
+			addAttributes(scopeTags, sm.Scope.Attributes)
+
+			for _, m := range sm.Metrics {
+				if m.Name == "" {
+					return nil, fmt.Errorf("Metric without name encountered")
+				}
+
+				newPoint := func(attributes []keyValue, timeUnixNano jsonUint64, fields map[string]interface{}) general.Point {
+					tags := general.CopyTags(scopeTags)
+					addAttributes(tags, attributes)
+					timestamp := currentTimestamp
+					if timeUnixNano != 0 {
+						timestamp = time.Unix(0, int64(timeUnixNano))
+					}
+					return general.Point{Measurement: m.Name, Fields: fields, Tags: tags, Timestamp: timestamp}
+				}
+
+				var numberDataPoints []numberDataPoint
+				switch {
+				case m.Gauge != nil:
+					numberDataPoints = m.Gauge.DataPoints
+				case m.Sum != nil:
+					numberDataPoints = m.Sum.DataPoints
+				case m.Histogram != nil:
+					for _, dp := range m.Histogram.DataPoints {
+						fields, err := histogramFields(dp)
+						if err != nil {
+							return nil, fmt.Errorf("Invalid histogram data point of metric %s: %w", m.Name, err)
+						}
+						ret = append(ret, newPoint(dp.Attributes, dp.TimeUnixNano, fields))
+					}
+				case m.Summary != nil:
+					for _, dp := range m.Summary.DataPoints {
+						ret = append(ret, newPoint(dp.Attributes, dp.TimeUnixNano, summaryFields(dp)))
+					}
+				}
+
+				for _, dp := range numberDataPoints {
+					var value interface{}
+					switch {
+					case dp.AsInt != nil:
+						value = int64(*dp.AsInt)
+					case dp.AsDouble != nil:
+						// neither NaN nor infinity can be represented in the outputs, so we skip those data points
+						if math.IsNaN(*dp.AsDouble) || math.IsInf(*dp.AsDouble, 0) {
+							continue
+						}
+						value = *dp.AsDouble
+					default:
+						continue
+					}
+					ret = append(ret, newPoint(dp.Attributes, dp.TimeUnixNano, map[string]interface{}{"value": value}))
+				}
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+func histogramFields(dp histogramDataPoint) (map[string]interface{}, error) {
+	if len(dp.BucketCounts) > 0 && len(dp.BucketCounts) != len(dp.ExplicitBounds)+1 {
+		return nil, fmt.Errorf("%d bucket counts do not match %d explicit bounds", len(dp.BucketCounts), len(dp.ExplicitBounds))
+	}
+
+	fields := map[string]interface{}{"count": uint64(dp.Count)}
+	setFiniteField(fields, "sum", dp.Sum)
+	setFiniteField(fields, "min", dp.Min)
+	setFiniteField(fields, "max", dp.Max)
+
+	var cumulative uint64
+	for i, count := range dp.BucketCounts {
+		cumulative += uint64(count)
+		if i < len(dp.ExplicitBounds) {
+			fields["le_"+strconv.FormatFloat(dp.ExplicitBounds[i], 'g', -1, 64)] = cumulative
+		} else {
+			fields["le_inf"] = cumulative
+		}
+	}
+	return fields, nil
+}
+
+func summaryFields(dp summaryDataPoint) map[string]interface{} {
+	fields := map[string]interface{}{"count": uint64(dp.Count)}
+	setFiniteField(fields, "sum", &dp.Sum)
+	for _, q := range dp.QuantileValues {
+		setFiniteField(fields, "quantile_"+strconv.FormatFloat(q.Quantile, 'g', -1, 64), &q.Value)
+	}
+	return fields
+}
+
+func setFiniteField(fields map[string]interface{}, key string, value *float64) {
+	if value != nil && !math.IsNaN(*value) && !math.IsInf(*value, 0) {
+		fields[key] = *value
+	}
+}
+
+func addAttributes(tags map[string]string, attributes []keyValue) {
+	for _, kv := range attributes {
+		if value := kv.Value.String(); value != "" {
+			tags[kv.Key] = value
+		}
+	}
+}
+
+// String formats the attribute value as tag value; arrays and key-value lists are formatted as JSON
+func (v anyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.ArrayValue != nil, v.KvlistValue != nil:
+		b, err := json.Marshal(v.plain())
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		value := v.plain()
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+func (v anyValue) plain() interface{} {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		values := make([]interface{}, 0, len(v.ArrayValue.Values))
+		for _, av := range v.ArrayValue.Values {
+			values = append(values, av.plain())
+		}
+		return values
+	case v.KvlistValue != nil:
+		values := make(map[string]interface{}, len(v.KvlistValue.Values))
+		for _, kv := range v.KvlistValue.Values {
+			values[kv.Key] = kv.Value.plain()
+		}
+		return values
+	case v.BytesValue != nil:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,214 @@
+package otlp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendStringField(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendFixed64Field(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func encodeStringAttribute(num protowire.Number, key string, value string) []byte {
+	var av []byte
+	av = appendStringField(av, 1, value)
+	var kv []byte
+	kv = appendStringField(kv, 1, key)
+	kv = appendMessage(kv, 2, av)
+	return appendMessage(nil, num, kv)
+}
+
+func encodeIntAttribute(num protowire.Number, key string, value int64) []byte {
+	var av []byte
+	av = protowire.AppendTag(av, 3, protowire.VarintType)
+	av = protowire.AppendVarint(av, uint64(value))
+	var kv []byte
+	kv = appendStringField(kv, 1, key)
+	kv = appendMessage(kv, 2, av)
+	return appendMessage(nil, num, kv)
+}
+
+func encodeRequest() []byte {
+	// gauge with a double, an int and a NaN data point
+	var gaugeMsg []byte
+	var dp []byte
+	dp = append(dp, encodeStringAttribute(7, "cpu", "cpu0")...)
+	dp = appendFixed64Field(dp, 3, 1613985840702000000)
+	dp = appendFixed64Field(dp, 4, math.Float64bits(0.5))
+	gaugeMsg = appendMessage(gaugeMsg, 1, dp)
+	dp = nil
+	dp = append(dp, encodeStringAttribute(7, "host", "override")...)
+	dp = appendFixed64Field(dp, 6, uint64(42))
+	gaugeMsg = appendMessage(gaugeMsg, 1, dp)
+	dp = nil
+	dp = appendFixed64Field(dp, 3, 1613985840702000000)
+	dp = appendFixed64Field(dp, 4, math.Float64bits(math.NaN()))
+	gaugeMsg = appendMessage(gaugeMsg, 1, dp)
+
+	var gaugeMetric []byte
+	gaugeMetric = appendStringField(gaugeMetric, 1, "cpu_usage")
+	gaugeMetric = appendStringField(gaugeMetric, 3, "1")
+	gaugeMetric = appendMessage(gaugeMetric, 5, gaugeMsg)
+
+	// histogram with packed bucket counts and bounds
+	dp = nil
+	dp = appendFixed64Field(dp, 3, 1613985840702000000)
+	dp = appendFixed64Field(dp, 4, 6)
+	dp = appendFixed64Field(dp, 5, math.Float64bits(2.5))
+	var counts, bounds []byte
+	for _, c := range []uint64{1, 2, 3} {
+		counts = protowire.AppendFixed64(counts, c)
+	}
+	for _, bound := range []float64{0.1, 1} {
+		bounds = protowire.AppendFixed64(bounds, math.Float64bits(bound))
+	}
+	dp = appendMessage(dp, 6, counts)
+	dp = appendMessage(dp, 7, bounds)
+	dp = append(dp, encodeIntAttribute(9, "status", 200)...)
+	histogramMsg := appendMessage(nil, 1, dp)
+	histogramMsg = protowire.AppendTag(histogramMsg, 2, protowire.VarintType)
+	histogramMsg = protowire.AppendVarint(histogramMsg, 2)
+
+	var histogramMetric []byte
+	histogramMetric = appendStringField(histogramMetric, 1, "request_duration")
+	histogramMetric = appendMessage(histogramMetric, 9, histogramMsg)
+
+	// summary
+	dp = nil
+	dp = appendFixed64Field(dp, 3, 1613985840702000000)
+	dp = appendFixed64Field(dp, 4, 10)
+	dp = appendFixed64Field(dp, 5, math.Float64bits(20))
+	var q []byte
+	q = appendFixed64Field(q, 1, math.Float64bits(0.99))
+	q = appendFixed64Field(q, 2, math.Float64bits(4))
+	dp = appendMessage(dp, 6, q)
+	summaryMsg := appendMessage(nil, 1, dp)
+
+	var summaryMetric []byte
+	summaryMetric = appendStringField(summaryMetric, 1, "gc_pause")
+	summaryMetric = appendMessage(summaryMetric, 11, summaryMsg)
+
+	var scope []byte
+	scope = appendStringField(scope, 1, "io.opentelemetry.runtime")
+	scope = appendStringField(scope, 2, "1.0.0")
+	scope = append(scope, encodeStringAttribute(3, "library", "runtime")...)
+
+	var sm []byte
+	sm = appendMessage(sm, 1, scope)
+	sm = appendMessage(sm, 2, gaugeMetric)
+	sm = appendMessage(sm, 2, histogramMetric)
+	sm = appendMessage(sm, 2, summaryMetric)
+
+	var res []byte
+	res = append(res, encodeStringAttribute(1, "service.name", "shop")...)
+	res = append(res, encodeStringAttribute(1, "host", "host1")...)
+
+	var rm []byte
+	rm = appendMessage(rm, 1, res)
+	rm = appendMessage(rm, 2, sm)
+	rm = appendStringField(rm, 3, "https://opentelemetry.io/schemas/1.9.0")
+
+	return appendMessage(nil, 1, rm)
+}
+
+func TestParseProtobuf(t *testing.T) {
+	currentTime := time.Now()
+	actual, err := ParseProtobuf(encodeRequest(), currentTime)
+	assert.Nil(t, err)
+
+	timestamp := time.Unix(0, 1613985840702000000)
+	expected := []general.Point{
+		{Measurement: "cpu_usage", Fields: map[string]interface{}{"value": 0.5}, Tags: map[string]string{"service.name": "shop", "host": "host1", "library": "runtime", "cpu": "cpu0"}, Timestamp: timestamp},
+		{Measurement: "cpu_usage", Fields: map[string]interface{}{"value": int64(42)}, Tags: map[string]string{"service.name": "shop", "host": "override", "library": "runtime"}, Timestamp: currentTime},
+		{Measurement: "request_duration", Fields: map[string]interface{}{"count": uint64(6), "sum": 2.5, "le_0.1": uint64(1), "le_1": uint64(3), "le_inf": uint64(6)}, Tags: map[string]string{"service.name": "shop", "host": "host1", "library": "runtime", "status": "200"}, Timestamp: timestamp},
+		{Measurement: "gc_pause", Fields: map[string]interface{}{"count": uint64(10), "sum": 20.0, "quantile_0.99": 4.0}, Tags: map[string]string{"service.name": "shop", "host": "host1", "library": "runtime"}, Timestamp: timestamp},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseProtobufInvalid(t *testing.T) {
+	_, err := ParseProtobuf([]byte{0x0a, 0x05, 0x01}, time.Now())
+	assert.NotNil(t, err)
+
+	// metric without name
+	sm := appendMessage(nil, 2, appendMessage(nil, 5, nil))
+	rm := appendMessage(nil, 2, sm)
+	_, err = ParseProtobuf(appendMessage(nil, 1, rm), time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestParseJSON(t *testing.T) {
+	body := `{
+		"resourceMetrics": [{
+			"resource": {"attributes": [
+				{"key": "service.name", "value": {"stringValue": "shop"}},
+				{"key": "deployment", "value": {"kvlistValue": {"values": [{"key": "canary", "value": {"boolValue": true}}]}}}
+			]},
+			"scopeMetrics": [{
+				"scope": {"name": "shop-metrics"},
+				"metrics": [
+					{
+						"name": "orders",
+						"unit": "1",
+						"sum": {
+							"aggregationTemporality": 2,
+							"isMonotonic": true,
+							"dataPoints": [
+								{"attributes": [{"key": "region", "value": {"stringValue": "eu"}}], "timeUnixNano": "1613985840702000000", "asInt": "17"},
+								{"attributes": [{"key": "ratio", "value": {"doubleValue": 0.5}}], "timeUnixNano": 1613985840702000000, "asDouble": 1.5}
+							]
+						}
+					},
+					{
+						"name": "latency",
+						"histogram": {
+							"dataPoints": [
+								{"timeUnixNano": "1613985840702000000", "count": "3", "sum": 0.7, "min": 0.1, "max": 0.4, "bucketCounts": ["1", "2"], "explicitBounds": [0.25]}
+							]
+						}
+					}
+				]
+			}]
+		}]
+	}`
+
+	actual, err := ParseJSON([]byte(body), time.Now())
+	assert.Nil(t, err)
+
+	timestamp := time.Unix(0, 1613985840702000000)
+	expected := []general.Point{
+		{Measurement: "orders", Fields: map[string]interface{}{"value": int64(17)}, Tags: map[string]string{"service.name": "shop", "deployment": `{"canary":true}`, "region": "eu"}, Timestamp: timestamp},
+		{Measurement: "orders", Fields: map[string]interface{}{"value": 1.5}, Tags: map[string]string{"service.name": "shop", "deployment": `{"canary":true}`, "ratio": "0.5"}, Timestamp: timestamp},
+		{Measurement: "latency", Fields: map[string]interface{}{"count": uint64(3), "sum": 0.7, "min": 0.1, "max": 0.4, "le_0.25": uint64(1), "le_inf": uint64(3)}, Tags: map[string]string{"service.name": "shop", "deployment": `{"canary":true}`}, Timestamp: timestamp},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseJSONInvalid(t *testing.T) {
+	invalidBodies := []string{
+		`{"resourceMetrics": [`,
+		`{"resourceMetrics": [{"scopeMetrics": [{"metrics": [{"gauge": {"dataPoints": [{"asInt": "x"}]}}]}]}]}`,
+		`{"resourceMetrics": [{"scopeMetrics": [{"metrics": [{"name": "a", "histogram": {"dataPoints": [{"bucketCounts": ["1"], "explicitBounds": [1]}]}}]}]}]}`,
+	}
+
+	for _, body := range invalidBodies {
+		_, err := ParseJSON([]byte(body), time.Now())
+		assert.NotNil(t, err, body)
+	}
+}
@@ -0,0 +1,372 @@
+package otlp
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/max-bytes/metrics-receiver/pkg/internal/protobuf"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// the decoders below only handle the fields that are needed for the conversion into points, all other fields are skipped
+
+func decodeExportMetricsServiceRequest(b []byte) (exportMetricsServiceRequest, error) {
+	var req exportMetricsServiceRequest
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 { // ExportMetricsServiceRequest.resource_metrics
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		return consumeEmbedded(typ, b, func(v []byte) error {
+			rm, err := decodeResourceMetrics(v)
+			req.ResourceMetrics = append(req.ResourceMetrics, rm)
+			return err
+		})
+	})
+	return req, err
+}
+
+func decodeResourceMetrics(b []byte) (resourceMetrics, error) {
+	var rm resourceMetrics
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1: // ResourceMetrics.resource
+			return consumeEmbedded(typ, b, func(v []byte) error {
+				return protobuf.ConsumeMessage(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+					if num != 1 { // Resource.attributes
+						return protowire.ConsumeFieldValue(num, typ, b), nil
+					}
+					return consumeKeyValue(typ, b, &rm.Resource.Attributes)
+				})
+			})
+		case 2: // ResourceMetrics.scope_metrics
+			return consumeEmbedded(typ, b, func(v []byte) error {
+				sm, err := decodeScopeMetrics(v)
+				rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+				return err
+			})
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return rm, err
+}
+
+func decodeScopeMetrics(b []byte) (scopeMetrics, error) {
+	var sm scopeMetrics
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1: // ScopeMetrics.scope
+			return consumeEmbedded(typ, b, func(v []byte) error {
+				return protobuf.ConsumeMessage(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+					switch num {
+					case 1: // InstrumentationScope.name
+						return consumeString(typ, b, &sm.Scope.Name)
+					case 2: // InstrumentationScope.version
+						return consumeString(typ, b, &sm.Scope.Version)
+					case 3: // InstrumentationScope.attributes
+						return consumeKeyValue(typ, b, &sm.Scope.Attributes)
+					default:
+						return protowire.ConsumeFieldValue(num, typ, b), nil
+					}
+				})
+			})
+		case 2: // ScopeMetrics.metrics
+			return consumeEmbedded(typ, b, func(v []byte) error {
+				m, err := decodeMetric(v)
+				sm.Metrics = append(sm.Metrics, m)
+				return err
+			})
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return sm, err
+}
+
+func decodeMetric(b []byte) (metric, error) {
+	var m metric
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1: // Metric.name
+			return consumeString(typ, b, &m.Name)
+		case 5: // Metric.gauge
+			m.Gauge = &gauge{}
+			return consumeDataPoints(typ, b, func(v []byte) error {
+				dp, err := decodeNumberDataPoint(v)
+				m.Gauge.DataPoints = append(m.Gauge.DataPoints, dp)
+				return err
+			})
+		case 7: // Metric.sum
+			m.Sum = &sum{}
+			return consumeDataPoints(typ, b, func(v []byte) error {
+				dp, err := decodeNumberDataPoint(v)
+				m.Sum.DataPoints = append(m.Sum.DataPoints, dp)
+				return err
+			})
+		case 9: // Metric.histogram
+			m.Histogram = &histogram{}
+			return consumeDataPoints(typ, b, func(v []byte) error {
+				dp, err := decodeHistogramDataPoint(v)
+				m.Histogram.DataPoints = append(m.Histogram.DataPoints, dp)
+				return err
+			})
+		case 11: // Metric.summary
+			m.Summary = &summary{}
+			return consumeDataPoints(typ, b, func(v []byte) error {
+				dp, err := decodeSummaryDataPoint(v)
+				m.Summary.DataPoints = append(m.Summary.DataPoints, dp)
+				return err
+			})
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return m, err
+}
+
+// consumeDataPoints consumes a Gauge, Sum, Histogram or Summary message, which all carry their data points in field 1
+func consumeDataPoints(typ protowire.Type, b []byte, decodeDataPoint func(v []byte) error) (int, error) {
+	return consumeEmbedded(typ, b, func(v []byte) error {
+		return protobuf.ConsumeMessage(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+			if num != 1 {
+				return protowire.ConsumeFieldValue(num, typ, b), nil
+			}
+			return consumeEmbedded(typ, b, decodeDataPoint)
+		})
+	})
+}
+
+func decodeNumberDataPoint(b []byte) (numberDataPoint, error) {
+	var dp numberDataPoint
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 7: // NumberDataPoint.attributes
+			return consumeKeyValue(typ, b, &dp.Attributes)
+		case num == 3 && typ == protowire.Fixed64Type: // NumberDataPoint.time_unix_nano
+			v, n := protowire.ConsumeFixed64(b)
+			dp.TimeUnixNano = jsonUint64(v)
+			return n, nil
+		case num == 4 && typ == protowire.Fixed64Type: // NumberDataPoint.as_double
+			v, n := protowire.ConsumeFixed64(b)
+			value := math.Float64frombits(v)
+			dp.AsDouble = &value
+			return n, nil
+		case num == 6 && typ == protowire.Fixed64Type: // NumberDataPoint.as_int
+			v, n := protowire.ConsumeFixed64(b)
+			value := jsonInt64(v)
+			dp.AsInt = &value
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return dp, err
+}
+
+func decodeHistogramDataPoint(b []byte) (histogramDataPoint, error) {
+	var dp histogramDataPoint
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 9: // HistogramDataPoint.attributes
+			return consumeKeyValue(typ, b, &dp.Attributes)
+		case num == 3 && typ == protowire.Fixed64Type: // HistogramDataPoint.time_unix_nano
+			v, n := protowire.ConsumeFixed64(b)
+			dp.TimeUnixNano = jsonUint64(v)
+			return n, nil
+		case num == 4 && typ == protowire.Fixed64Type: // HistogramDataPoint.count
+			v, n := protowire.ConsumeFixed64(b)
+			dp.Count = jsonUint64(v)
+			return n, nil
+		case num == 5 && typ == protowire.Fixed64Type: // HistogramDataPoint.sum
+			return consumeDouble(b, &dp.Sum)
+		case num == 6: // HistogramDataPoint.bucket_counts
+			return consumeRepeatedFixed64(typ, b, func(v uint64) {
+				dp.BucketCounts = append(dp.BucketCounts, jsonUint64(v))
+			})
+		case num == 7: // HistogramDataPoint.explicit_bounds
+			return consumeRepeatedFixed64(typ, b, func(v uint64) {
+				dp.ExplicitBounds = append(dp.ExplicitBounds, math.Float64frombits(v))
+			})
+		case num == 11 && typ == protowire.Fixed64Type: // HistogramDataPoint.min
+			return consumeDouble(b, &dp.Min)
+		case num == 12 && typ == protowire.Fixed64Type: // HistogramDataPoint.max
+			return consumeDouble(b, &dp.Max)
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return dp, err
+}
+
+func decodeSummaryDataPoint(b []byte) (summaryDataPoint, error) {
+	var dp summaryDataPoint
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 7: // SummaryDataPoint.attributes
+			return consumeKeyValue(typ, b, &dp.Attributes)
+		case num == 3 && typ == protowire.Fixed64Type: // SummaryDataPoint.time_unix_nano
+			v, n := protowire.ConsumeFixed64(b)
+			dp.TimeUnixNano = jsonUint64(v)
+			return n, nil
+		case num == 4 && typ == protowire.Fixed64Type: // SummaryDataPoint.count
+			v, n := protowire.ConsumeFixed64(b)
+			dp.Count = jsonUint64(v)
+			return n, nil
+		case num == 5 && typ == protowire.Fixed64Type: // SummaryDataPoint.sum
+			v, n := protowire.ConsumeFixed64(b)
+			dp.Sum = math.Float64frombits(v)
+			return n, nil
+		case num == 6: // SummaryDataPoint.quantile_values
+			return consumeEmbedded(typ, b, func(v []byte) error {
+				var q valueAtQuantile
+				err := protobuf.ConsumeMessage(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+					if typ != protowire.Fixed64Type || (num != 1 && num != 2) { // ValueAtQuantile.quantile, ValueAtQuantile.value
+						return protowire.ConsumeFieldValue(num, typ, b), nil
+					}
+					v, n := protowire.ConsumeFixed64(b)
+					if num == 1 {
+						q.Quantile = math.Float64frombits(v)
+					} else {
+						q.Value = math.Float64frombits(v)
+					}
+					return n, nil
+				})
+				dp.QuantileValues = append(dp.QuantileValues, q)
+				return err
+			})
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return dp, err
+}
+
+func consumeKeyValue(typ protowire.Type, b []byte, attributes *[]keyValue) (int, error) {
+	return consumeEmbedded(typ, b, func(v []byte) error {
+		var kv keyValue
+		err := protobuf.ConsumeMessage(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+			switch num {
+			case 1: // KeyValue.key
+				return consumeString(typ, b, &kv.Key)
+			case 2: // KeyValue.value
+				return consumeEmbedded(typ, b, func(v []byte) error {
+					var err error
+					kv.Value, err = decodeAnyValue(v)
+					return err
+				})
+			default:
+				return protowire.ConsumeFieldValue(num, typ, b), nil
+			}
+		})
+		*attributes = append(*attributes, kv)
+		return err
+	})
+}
+
+func decodeAnyValue(b []byte) (anyValue, error) {
+	var av anyValue
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1: // AnyValue.string_value
+			var s string
+			av.StringValue = &s
+			return consumeString(typ, b, av.StringValue)
+		case num == 2 && typ == protowire.VarintType: // AnyValue.bool_value
+			v, n := protowire.ConsumeVarint(b)
+			value := protowire.DecodeBool(v)
+			av.BoolValue = &value
+			return n, nil
+		case num == 3 && typ == protowire.VarintType: // AnyValue.int_value
+			v, n := protowire.ConsumeVarint(b)
+			value := jsonInt64(v)
+			av.IntValue = &value
+			return n, nil
+		case num == 4 && typ == protowire.Fixed64Type: // AnyValue.double_value
+			return consumeDouble(b, &av.DoubleValue)
+		case num == 5: // AnyValue.array_value
+			av.ArrayValue = &arrayValue{}
+			return consumeEmbedded(typ, b, func(v []byte) error {
+				return protobuf.ConsumeMessage(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+					if num != 1 { // ArrayValue.values
+						return protowire.ConsumeFieldValue(num, typ, b), nil
+					}
+					return consumeEmbedded(typ, b, func(v []byte) error {
+						value, err := decodeAnyValue(v)
+						av.ArrayValue.Values = append(av.ArrayValue.Values, value)
+						return err
+					})
+				})
+			})
+		case num == 6: // AnyValue.kvlist_value
+			av.KvlistValue = &kvlistValue{}
+			return consumeEmbedded(typ, b, func(v []byte) error {
+				return protobuf.ConsumeMessage(v, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+					if num != 1 { // KeyValueList.values
+						return protowire.ConsumeFieldValue(num, typ, b), nil
+					}
+					return consumeKeyValue(typ, b, &av.KvlistValue.Values)
+				})
+			})
+		case num == 7 && typ == protowire.BytesType: // AnyValue.bytes_value
+			v, n := protowire.ConsumeBytes(b)
+			av.BytesValue = append([]byte{}, v...)
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return av, err
+}
+
+func consumeEmbedded(typ protowire.Type, b []byte, decode func(v []byte) error) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, fmt.Errorf("Unexpected wire type %d for embedded message", typ)
+	}
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, nil
+	}
+	return n, decode(v)
+}
+
+func consumeString(typ protowire.Type, b []byte, s *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, fmt.Errorf("Unexpected wire type %d for string", typ)
+	}
+	v, n := protowire.ConsumeString(b)
+	*s = v
+	return n, nil
+}
+
+func consumeDouble(b []byte, d **float64) (int, error) {
+	v, n := protowire.ConsumeFixed64(b)
+	value := math.Float64frombits(v)
+	*d = &value
+	return n, nil
+}
+
+// consumeRepeatedFixed64 consumes a repeated fixed64 or double field, which is usually packed but may also be sent unpacked
+func consumeRepeatedFixed64(typ protowire.Type, b []byte, add func(v uint64)) (int, error) {
+	switch typ {
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(b)
+		add(v)
+		return n, nil
+	case protowire.BytesType:
+		packed, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		for len(packed) > 0 {
+			v, m := protowire.ConsumeFixed64(packed)
+			if m < 0 {
+				return m, nil
+			}
+			add(v)
+			packed = packed[m:]
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("Unexpected wire type %d for repeated fixed64", typ)
+	}
+}
@@ -0,0 +1,296 @@
+package perfdata
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// measurements the perfdata is written to
+const (
+	MetricMeasurement = "metric"
+	StateMeasurement  = "state"
+)
+
+// Value is a single performance data value of the form 'label'=value[UOM];[warn];[crit];[min];[max]; missing or
+// undetermined ("U") values are nil
+type Value struct {
+	Label string
+	Value *float64
+	UOM   string
+	Warn  *float64
+	Crit  *float64
+	Min   *float64
+	Max   *float64
+}
+
+// ParsePerfdata parses a plugin performance data string, i.e. space separated values of the form 'label'=value[UOM];[warn];[crit];[min];[max]
+func ParsePerfdata(perfdata string) ([]Value, error) {
+	var ret []Value
+
+	s := strings.TrimSpace(perfdata)
+	for s != "" {
+		var label string
+		if s[0] == '\'' {
+			// quoted labels may contain spaces and equal signs, a quote inside the label is written as two quotes
+			var sb strings.Builder
+			i := 1
+			for {
+				if i >= len(s) {
+					return nil, errors.New("unterminated quoted label")
+				}
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						sb.WriteByte('\'')
+						i += 2
+						continue
+					}
+					break
+				}
+				sb.WriteByte(s[i])
+				i++
+			}
+			label = sb.String()
+			s = s[i+1:]
+			if !strings.HasPrefix(s, "=") {
+				return nil, fmt.Errorf("expected \"=\" after label \"%s\"", label)
+			}
+		} else {
+			eq := strings.IndexByte(s, '=')
+			if eq < 0 {
+				return nil, fmt.Errorf("expected \"label=value\" in \"%s\"", s)
+			}
+			label = s[:eq]
+			if strings.ContainsAny(label, " \t") {
+				return nil, fmt.Errorf("unquoted label \"%s\" must not contain spaces", label)
+			}
+			s = s[eq:]
+		}
+		if label == "" {
+			return nil, errors.New("empty label")
+		}
+
+		s = s[1:] // skip "="
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		v, err := parseValue(label, s[:end])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+		s = strings.TrimSpace(s[end:])
+	}
+
+	return ret, nil
+}
+
+func parseValue(label string, s string) (Value, error) {
+	v := Value{Label: label}
+	parts := strings.Split(s, ";")
+	if len(parts) > 5 {
+		return Value{}, fmt.Errorf("too many values for label \"%s\"", label)
+	}
+
+	// the value ([-0-9.]) is directly followed by the unit of measurement
+	valueStr := parts[0]
+	var err error
+	if valueStr != "U" {
+		i := 0
+		for i < len(valueStr) && strings.IndexByte("0123456789.,-", valueStr[i]) >= 0 {
+			i++
+		}
+		if i == 0 {
+			return Value{}, fmt.Errorf("missing value for label \"%s\"", label)
+		}
+		v.UOM = valueStr[i:]
+		if v.Value, err = parseNumber(valueStr[:i]); err != nil {
+			return Value{}, fmt.Errorf("invalid value \"%s\" for label \"%s\"", valueStr, label)
+		}
+	}
+
+	targets := []**float64{&v.Warn, &v.Crit, &v.Min, &v.Max}
+	for j, part := range parts[1:] {
+		if j < 2 {
+			part = thresholdValue(part)
+		}
+		if *targets[j], err = parseNumber(part); err != nil {
+			return Value{}, fmt.Errorf("invalid value \"%s\" for label \"%s\"", part, label)
+		}
+	}
+
+	return v, nil
+}
+
+// thresholdValue reduces a threshold range ("10", "10:", "~:10", "10:20", "@10:20") to a single value: the end of the range
+// if there is one, otherwise the start
+func thresholdValue(s string) string {
+	s = strings.TrimPrefix(s, "@")
+	colon := strings.IndexByte(s, ':')
+	if colon < 0 {
+		return s
+	}
+	if end := s[colon+1:]; end != "" {
+		return end
+	}
+	if start := s[:colon]; start != "~" {
+		return start
+	}
+	return ""
+}
+
+// parseNumber parses a number (with either "." or "," as decimal separator); empty strings and "U" result in nil
+func parseNumber(s string) (*float64, error) {
+	if s == "" || s == "U" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("invalid number \"%s\"", s)
+	}
+	return &f, nil
+}
+
+// states of services and hosts; the numeric value is stored in the state measurement
+var states = map[string]int64{
+	"OK":          0,
+	"WARNING":     1,
+	"CRITICAL":    2,
+	"UNKNOWN":     3,
+	"UP":          0,
+	"DOWN":        1,
+	"UNREACHABLE": 2,
+}
+
+// Parse parses lines in the format of the nagios/icinga perfdata files (as used by PNP4Nagios and the icinga2 PerfdataWriter), i.e. tab
+// separated KEY::VALUE pairs like "DATATYPE::SERVICEPERFDATA\tTIMET::1613985840\tHOSTNAME::host1\tSERVICEDESC::disk\tSERVICEPERFDATA::'/'=10GB;15;18;0;20\tSERVICESTATE::OK".
+// Every perfdata value results in a point of the measurement "metric" (tags host, service, label, uom; fields value, warn, crit, min, max)
+// and the state in a point of the measurement "state" (tags host, service; field value). Host checks (DATATYPE::HOSTPERFDATA) use the
+// HOST* keys and have no service tag. Points without TIMET get currentTime
+func Parse(input []byte, currentTime time.Time) ([]general.Point, []error) {
+	var ret []general.Point
+	var errs []error
+
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	// the input is already in memory (and bounded by the maximum body size), so lines of any length are accepted
+	scanner.Buffer(nil, len(input)+1)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		points, err := ParseLine(line, currentTime)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNumber, err))
+			continue
+		}
+		ret = append(ret, points...)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return ret, errs
+}
+
+// ParseLine parses a single perfdata file line, see Parse
+func ParseLine(line string, currentTime time.Time) ([]general.Point, error) {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(line, "\t") {
+		kv := strings.SplitN(pair, "::", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("expected \"KEY::VALUE\", got \"%s\"", pair)
+		}
+		values[kv[0]] = strings.TrimSpace(kv[1])
+	}
+
+	var prefix string
+	switch values["DATATYPE"] {
+	case "SERVICEPERFDATA", "":
+		prefix = "SERVICE"
+	case "HOSTPERFDATA":
+		prefix = "HOST"
+	default:
+		return nil, fmt.Errorf("unknown DATATYPE \"%s\"", values["DATATYPE"])
+	}
+
+	tags := make(map[string]string)
+	if tags["host"] = values["HOSTNAME"]; tags["host"] == "" {
+		return nil, errors.New("missing HOSTNAME")
+	}
+	if prefix == "SERVICE" {
+		if tags["service"] = values["SERVICEDESC"]; tags["service"] == "" {
+			return nil, errors.New("missing SERVICEDESC")
+		}
+	}
+
+	timestamp := currentTime
+	if timet, ok := values["TIMET"]; ok {
+		t, err := strconv.ParseInt(timet, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TIMET \"%s\"", timet)
+		}
+		timestamp = time.Unix(t, 0)
+	}
+
+	perfdata, err := ParsePerfdata(values[prefix+"PERFDATA"])
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []general.Point
+	for _, v := range perfdata {
+		pointTags := general.CopyTags(tags)
+		pointTags["label"] = v.Label
+		if v.UOM != "" {
+			pointTags["uom"] = v.UOM
+		}
+
+		fields := make(map[string]interface{})
+		for name, value := range map[string]*float64{"value": v.Value, "warn": v.Warn, "crit": v.Crit, "min": v.Min, "max": v.Max} {
+			if value != nil {
+				fields[name] = *value
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		ret = append(ret, general.Point{Measurement: MetricMeasurement, Fields: fields, Tags: pointTags, Timestamp: timestamp})
+	}
+
+	if state, ok, err := parseState(values, prefix); err != nil {
+		return nil, err
+	} else if ok {
+		ret = append(ret, general.Point{Measurement: StateMeasurement, Fields: map[string]interface{}{"value": state}, Tags: general.CopyTags(tags), Timestamp: timestamp})
+	}
+
+	return ret, nil
+}
+
+// parseState reads the state from SERVICESTATEID/HOSTSTATEID or the state name in SERVICESTATE/HOSTSTATE
+func parseState(values map[string]string, prefix string) (int64, bool, error) {
+	if stateID, ok := values[prefix+"STATEID"]; ok && stateID != "" {
+		state, err := strconv.ParseInt(stateID, 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid %sSTATEID \"%s\"", prefix, stateID)
+		}
+		return state, true, nil
+	}
+	if stateName, ok := values[prefix+"STATE"]; ok && stateName != "" {
+		state, ok := states[strings.ToUpper(stateName)]
+		if !ok {
+			return 0, false, fmt.Errorf("unknown %sSTATE \"%s\"", prefix, stateName)
+		}
+		return state, true, nil
+	}
+	return 0, false, nil
+}
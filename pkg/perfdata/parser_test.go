@@ -0,0 +1,110 @@
+package perfdata
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestParsePerfdata(t *testing.T) {
+	actual, err := ParsePerfdata(`'/ usage'=10.5GB;15;18;0;20 time=0,25s;;;; load1=0.5;@1:5;~:10 'it''s'=U users=3 inodes=80%;10:;20:`)
+	assert.Nil(t, err)
+
+	expected := []Value{
+		{Label: "/ usage", Value: float(10.5), UOM: "GB", Warn: float(15), Crit: float(18), Min: float(0), Max: float(20)},
+		{Label: "time", Value: float(0.25), UOM: "s"},
+		{Label: "load1", Value: float(0.5), Warn: float(5), Crit: float(10)},
+		{Label: "it's"},
+		{Label: "users", Value: float(3)},
+		{Label: "inodes", Value: float(80), UOM: "%", Warn: float(10), Crit: float(20)},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseInvalidPerfdata(t *testing.T) {
+	invalidPerfdata := []string{
+		"load1",
+		"'load1=1",
+		"'load1' 1",
+		"=1",
+		"load1=",
+		"load1=abc",
+		"load1=1;2;3;4;5;6",
+		"load1=1;abc",
+		"load1=1..5",
+	}
+
+	for _, perfdata := range invalidPerfdata {
+		_, err := ParsePerfdata(perfdata)
+		assert.NotNil(t, err, perfdata)
+	}
+}
+
+func sortPoints(points []general.Point) {
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Measurement+points[i].Tags["label"] < points[j].Measurement+points[j].Tags["label"]
+	})
+}
+
+func TestParse(t *testing.T) {
+	lines := []string{
+		"DATATYPE::SERVICEPERFDATA\tTIMET::1613985840\tHOSTNAME::host1\tSERVICEDESC::disk\tSERVICEPERFDATA::'/'=10GB;15;18;0;20 '/var'=U\tSERVICECHECKCOMMAND::check_disk\tSERVICESTATE::WARNING\tSERVICESTATETYPE::HARD",
+		"",
+		"DATATYPE::HOSTPERFDATA\tTIMET::1613985840\tHOSTNAME::host1\tHOSTPERFDATA::rta=0.5ms;100;500;0 pl=0%;20;60;0;100\tHOSTSTATEID::0",
+		"DATATYPE::SERVICEPERFDATA\tHOSTNAME::host2\tSERVICEDESC::ping\tSERVICEPERFDATA::\tSERVICESTATE::ok",
+	}
+
+	currentTime := time.Now()
+	actual, errs := Parse([]byte(strings.Join(lines, "\n")), currentTime)
+	assert.Empty(t, errs)
+	sortPoints(actual)
+
+	timestamp := time.Unix(1613985840, 0)
+	expected := []general.Point{
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 10.0, "warn": 15.0, "crit": 18.0, "min": 0.0, "max": 20.0}, Tags: map[string]string{"host": "host1", "service": "disk", "label": "/", "uom": "GB"}, Timestamp: timestamp},
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 0.0, "warn": 20.0, "crit": 60.0, "min": 0.0, "max": 100.0}, Tags: map[string]string{"host": "host1", "label": "pl", "uom": "%"}, Timestamp: timestamp},
+		{Measurement: "metric", Fields: map[string]interface{}{"value": 0.5, "warn": 100.0, "crit": 500.0, "min": 0.0}, Tags: map[string]string{"host": "host1", "label": "rta", "uom": "ms"}, Timestamp: timestamp},
+		{Measurement: "state", Fields: map[string]interface{}{"value": int64(1)}, Tags: map[string]string{"host": "host1", "service": "disk"}, Timestamp: timestamp},
+		{Measurement: "state", Fields: map[string]interface{}{"value": int64(0)}, Tags: map[string]string{"host": "host1"}, Timestamp: timestamp},
+		{Measurement: "state", Fields: map[string]interface{}{"value": int64(0)}, Tags: map[string]string{"host": "host2", "service": "ping"}, Timestamp: currentTime},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseInvalidLines(t *testing.T) {
+	lines := []string{
+		"HOSTNAME::host1 SERVICEDESC::disk",                                   // not tab separated
+		"DATATYPE::FOO\tHOSTNAME::host1",                                      // unknown datatype
+		"DATATYPE::SERVICEPERFDATA\tSERVICEDESC::disk\tSERVICESTATE::OK",      // no host
+		"DATATYPE::SERVICEPERFDATA\tHOSTNAME::host1\tSERVICESTATE::OK",        // no service
+		"HOSTNAME::host1\tSERVICEDESC::disk\tTIMET::yesterday",                // invalid timestamp
+		"HOSTNAME::host1\tSERVICEDESC::disk\tSERVICEPERFDATA::a",              // invalid perfdata
+		"HOSTNAME::host1\tSERVICEDESC::disk\tSERVICESTATE::BROKEN",            // invalid state
+		"HOSTNAME::host1\tSERVICEDESC::disk\tSERVICESTATEID::x",               // invalid state id
+		"HOSTNAME::host1\tSERVICEDESC::disk\tSERVICEPERFDATA::a=1\tTIMET::10", // valid
+	}
+
+	actual, errs := Parse([]byte(strings.Join(lines, "\n")), time.Now())
+	assert.Len(t, errs, 8)
+	assert.Len(t, actual, 1)
+}
+
+func TestParseLongLines(t *testing.T) {
+	// lines longer than the default token size of bufio.Scanner (e.g. checks with many perfdata values) don't end the parsing
+	lines := []string{
+		"HOSTNAME::host1\tSERVICEDESC::disk\tSERVICEPERFDATA::" + strings.Repeat("a=1 ", 20000),
+		"HOSTNAME::host1\tSERVICEDESC::ping\tSERVICEPERFDATA::rta=1",
+	}
+
+	actual, errs := Parse([]byte(strings.Join(lines, "\n")), time.Now())
+	assert.Empty(t, errs)
+	assert.Len(t, actual, 20001)
+}
@@ -0,0 +1,152 @@
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/max-bytes/metrics-receiver/pkg/internal/protobuf"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// name of the label that carries the metric name in prometheus time series
+const metricNameLabel = "__name__"
+
+// ErrDecodedTooLarge is returned by ParseRemoteWrite if the decompressed request exceeds the maximum size
+var ErrDecodedTooLarge = errors.New("Decompressed remote write request too large")
+
+// ParseRemoteWrite decodes a snappy-compressed protobuf WriteRequest (as sent by prometheus remote_write)
+// and converts every sample into a point: metric name -> measurement, labels -> tags, sample value -> field "value".
+// Requests whose decompressed size exceeds maxDecodedSize bytes (0 = unlimited) are rejected before they are decompressed.
+func ParseRemoteWrite(body []byte, maxDecodedSize int64) ([]general.Point, error) {
+	// snappy allocates the decoded length declared in the header, which a small body can set to several GiB
+	decodedLen, err := snappy.DecodedLen(body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress remote write request: %w", err)
+	}
+	if maxDecodedSize > 0 && int64(decodedLen) > maxDecodedSize {
+		return nil, ErrDecodedTooLarge
+	}
+
+	decoded, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress remote write request: %w", err)
+	}
+
+	var ret []general.Point
+	err = protobuf.ConsumeMessage(decoded, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 || typ != protowire.BytesType { // WriteRequest.timeseries
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		points, err := parseTimeSeries(v)
+		if err != nil {
+			return 0, err
+		}
+		ret = append(ret, points...)
+		return n, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode remote write request: %w", err)
+	}
+
+	return ret, nil
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+func parseTimeSeries(b []byte) ([]general.Point, error) {
+	tags := make(map[string]string)
+	var samples []sample
+
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.BytesType || (num != 1 && num != 2) { // TimeSeries.labels, TimeSeries.samples
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n, nil
+		}
+		var err error
+		if num == 1 {
+			var name, value string
+			name, value, err = parseLabel(v)
+			tags[name] = value
+		} else {
+			var s sample
+			s, err = parseSample(v)
+			samples = append(samples, s)
+		}
+		return n, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	measurement, ok := tags[metricNameLabel]
+	if !ok || measurement == "" {
+		return nil, fmt.Errorf("Time series without metric name encountered")
+	}
+	delete(tags, metricNameLabel)
+
+	ret := make([]general.Point, 0, len(samples))
+	for _, s := range samples {
+		// NaN is used by prometheus as staleness marker and neither NaN nor infinity can be represented in the outputs, so we skip those samples
+		if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+			continue
+		}
+
+		ret = append(ret, general.Point{
+			Measurement: measurement,
+			Fields:      map[string]interface{}{"value": s.value},
+			Tags:        general.CopyTags(tags),
+			Timestamp:   time.Unix(0, s.timestamp*int64(time.Millisecond)),
+		})
+	}
+	return ret, nil
+}
+
+func parseLabel(b []byte) (string, string, error) {
+	var name, value string
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.BytesType || (num != 1 && num != 2) { // Label.name, Label.value
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+		v, n := protowire.ConsumeString(b)
+		if num == 1 {
+			name = v
+		} else {
+			value = v
+		}
+		return n, nil
+	})
+	return name, value, err
+}
+
+func parseSample(b []byte) (sample, error) {
+	var s sample
+	err := protobuf.ConsumeMessage(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type: // Sample.value
+			v, n := protowire.ConsumeFixed64(b)
+			s.value = math.Float64frombits(v)
+			return n, nil
+		case num == 2 && typ == protowire.VarintType: // Sample.timestamp
+			v, n := protowire.ConsumeVarint(b)
+			s.timestamp = int64(v)
+			return n, nil
+		default:
+			return protowire.ConsumeFieldValue(num, typ, b), nil
+		}
+	})
+	return s, err
+}
@@ -0,0 +1,97 @@
+package prometheus
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testSeries struct {
+	labels  [][2]string
+	samples []sample
+}
+
+func encodeWriteRequest(series []testSeries) []byte {
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l[0])
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l[1])
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+		for _, smp := range s.samples {
+			var sb []byte
+			sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+			sb = protowire.AppendFixed64(sb, math.Float64bits(smp.value))
+			sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+			sb = protowire.AppendVarint(sb, uint64(smp.timestamp))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sb)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return snappy.Encode(nil, req)
+}
+
+func TestParseRemoteWrite(t *testing.T) {
+	body := encodeWriteRequest([]testSeries{
+		{
+			labels:  [][2]string{{"__name__", "up"}, {"job", "node"}, {"instance", "host1:9100"}},
+			samples: []sample{{value: 1, timestamp: 1613985840702}, {value: 0, timestamp: 1613985850702}},
+		},
+		{
+			labels:  [][2]string{{"__name__", "node_load1"}},
+			samples: []sample{{value: 0.25, timestamp: 1613985840702}, {value: math.NaN(), timestamp: 1613985850702}},
+		},
+	})
+
+	actual, err := ParseRemoteWrite(body, 0)
+	assert.Nil(t, err)
+
+	expected := []general.Point{
+		{Measurement: "up", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"job": "node", "instance": "host1:9100"}, Timestamp: time.Unix(0, 1613985840702*int64(time.Millisecond))},
+		{Measurement: "up", Fields: map[string]interface{}{"value": 0.0}, Tags: map[string]string{"job": "node", "instance": "host1:9100"}, Timestamp: time.Unix(0, 1613985850702*int64(time.Millisecond))},
+		{Measurement: "node_load1", Fields: map[string]interface{}{"value": 0.25}, Tags: map[string]string{}, Timestamp: time.Unix(0, 1613985840702*int64(time.Millisecond))},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseRemoteWriteWithoutMetricName(t *testing.T) {
+	body := encodeWriteRequest([]testSeries{
+		{labels: [][2]string{{"job", "node"}}, samples: []sample{{value: 1, timestamp: 1613985840702}}},
+	})
+
+	_, err := ParseRemoteWrite(body, 0)
+	assert.NotNil(t, err)
+}
+
+func TestParseRemoteWriteInvalidBody(t *testing.T) {
+	_, err := ParseRemoteWrite([]byte("not snappy"), 0)
+	assert.NotNil(t, err)
+
+	_, err = ParseRemoteWrite(snappy.Encode(nil, []byte{0x0a, 0xff}), 0)
+	assert.NotNil(t, err)
+}
+
+func TestParseRemoteWriteDecodedTooLarge(t *testing.T) {
+	body := encodeWriteRequest([]testSeries{
+		{labels: [][2]string{{"__name__", "up"}}, samples: []sample{{value: 1, timestamp: 1613985840702}}},
+	})
+	_, err := ParseRemoteWrite(body, 10)
+	assert.Equal(t, ErrDecodedTooLarge, err)
+
+	// a header declaring a decoded length of ~4 GiB is rejected without decoding
+	_, err = ParseRemoteWrite([]byte{0xff, 0xff, 0xff, 0xff, 0x0f, 0x00}, 1024)
+	assert.Equal(t, ErrDecodedTooLarge, err)
+}
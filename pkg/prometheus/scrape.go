@@ -0,0 +1,89 @@
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// name of the label that identifies the scraped target, like prometheus sets it
+const instanceLabel = "instance"
+
+// Scraper fetches the metrics of a target serving the prometheus text exposition format
+type Scraper struct {
+	url     string
+	labels  map[string]string
+	client  *http.Client
+	maxSize int64
+}
+
+// NewScraper creates a scraper for the target url; every scraped point gets the tag "instance" (host:port of the target) and the
+// configured labels, which take precedence over the labels of the scraped samples. A response body larger than maxSize bytes fails
+// the scrape.
+func NewScraper(targetURL string, labels map[string]string, timeout time.Duration, maxSize int64) (*Scraper, error) {
+	parsed, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid scrape target url %s: %w", targetURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid scrape target url %s: scheme must be http or https", targetURL)
+	}
+
+	targetLabels := map[string]string{instanceLabel: parsed.Host}
+	for k, v := range labels {
+		targetLabels[k] = v
+	}
+
+	return &Scraper{
+		url:     targetURL,
+		labels:  targetLabels,
+		client:  &http.Client{Timeout: timeout},
+		maxSize: maxSize,
+	}, nil
+}
+
+// Scrape fetches and parses the metrics of the target; it returns the points and the size of the response body
+func (s *Scraper) Scrape(now time.Time) ([]general.Point, int, error) {
+	req, err := http.NewRequest("GET", s.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to scrape %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("Failed to scrape %s: unexpected status %s", s.url, resp.Status)
+	}
+
+	// one byte more than allowed is read to detect a response exceeding the limit
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, s.maxSize+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to read response of %s: %w", s.url, err)
+	}
+	if int64(len(body)) > s.maxSize {
+		return nil, len(body), fmt.Errorf("Failed to read response of %s: response exceeds the maximum size of %d bytes", s.url, s.maxSize)
+	}
+
+	points, err := ParseText(body, now)
+	if err != nil {
+		return nil, len(body), fmt.Errorf("Failed to parse response of %s: %w", s.url, err)
+	}
+
+	for _, p := range points {
+		for k, v := range s.labels {
+			p.Tags[k] = v
+		}
+	}
+
+	return points, len(body), nil
+}
@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrape(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up{job=\"exporter\",env=\"dev\"} 1\n"))
+	}))
+	defer server.Close()
+
+	scraper, err := NewScraper(server.URL+"/metrics", map[string]string{"env": "prod"}, time.Second, 1024)
+	assert.Nil(t, err)
+
+	now := time.Now()
+	actual, size, err := scraper.Scrape(now)
+	assert.Nil(t, err)
+	assert.Equal(t, 31, size)
+
+	expected := []general.Point{
+		{Measurement: "up", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"job": "exporter", "env": "prod", "instance": strings.TrimPrefix(server.URL, "http://")}, Timestamp: now},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestScrapeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/invalid":
+			w.Write([]byte("up{\n"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("up 1\n", 10)))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte("up 1\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/invalid", "/large", "/slow", "/missing"} {
+		scraper, err := NewScraper(server.URL+path, nil, 100*time.Millisecond, 40)
+		assert.Nil(t, err)
+		_, _, err = scraper.Scrape(time.Now())
+		assert.NotNil(t, err, path)
+	}
+
+	_, err := NewScraper("ftp://localhost/metrics", nil, time.Second, 1024)
+	assert.NotNil(t, err)
+}
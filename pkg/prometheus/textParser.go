@@ -0,0 +1,151 @@
+package prometheus
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// ParseText parses the prometheus text exposition format (as served on the /metrics endpoints of exporters) and converts every sample
+// into a point like ParseRemoteWrite does: metric name -> measurement, labels -> tags, sample value -> field "value".
+// Comments (HELP and TYPE lines) are ignored, histograms and summaries result in their _bucket, _sum and _count series.
+// Samples without timestamp get currentTimestamp. Like prometheus does, the whole input is rejected if any line is invalid
+func ParseText(input []byte, currentTimestamp time.Time) ([]general.Point, error) {
+	var ret []general.Point
+
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		point, ok, err := parseSampleLine(line, currentTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sample in line %d: %w", lineNumber, err)
+		}
+		if ok {
+			ret = append(ret, point)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// parseSampleLine parses a line "metric_name[{label="value",...}] value [timestamp]"; samples with NaN or infinite values are skipped
+func parseSampleLine(line string, currentTimestamp time.Time) (general.Point, bool, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end < 0 {
+		return general.Point{}, false, errors.New("expected \"metric_name value\"")
+	}
+	measurement := line[:end]
+	if measurement == "" {
+		return general.Point{}, false, errors.New("missing metric name")
+	}
+	rest := line[end:]
+
+	tags := make(map[string]string)
+	if rest[0] == '{' {
+		var err error
+		rest, err = parseLabels(rest[1:], tags)
+		if err != nil {
+			return general.Point{}, false, err
+		}
+	}
+
+	tokens := strings.Fields(rest)
+	if len(tokens) != 1 && len(tokens) != 2 {
+		return general.Point{}, false, errors.New("expected value and optional timestamp after the metric")
+	}
+
+	value, err := strconv.ParseFloat(tokens[0], 64)
+	if err != nil {
+		return general.Point{}, false, fmt.Errorf("invalid value \"%s\"", tokens[0])
+	}
+
+	timestamp := currentTimestamp
+	if len(tokens) == 2 {
+		ms, err := strconv.ParseInt(tokens[1], 10, 64)
+		if err != nil {
+			return general.Point{}, false, fmt.Errorf("invalid timestamp \"%s\"", tokens[1])
+		}
+		timestamp = time.Unix(0, ms*int64(time.Millisecond))
+	}
+
+	// neither NaN nor infinity can be represented in the outputs
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return general.Point{}, false, nil
+	}
+
+	return general.Point{
+		Measurement: measurement,
+		Fields:      map[string]interface{}{"value": value},
+		Tags:        tags,
+		Timestamp:   timestamp,
+	}, true, nil
+}
+
+// parseLabels parses the labels after the opening brace and returns the remaining input after the closing brace
+func parseLabels(s string, tags map[string]string) (string, error) {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return "", errors.New("unterminated label set")
+		}
+		if s[0] == '}' {
+			return s[1:], nil
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return "", errors.New("expected label=\"value\"")
+		}
+		name := strings.TrimSpace(s[:eq])
+		if name == "" {
+			return "", errors.New("empty label name")
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		if s == "" || s[0] != '"' {
+			return "", fmt.Errorf("expected quoted value for label \"%s\"", name)
+		}
+
+		var value strings.Builder
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case '\\', '"':
+					value.WriteByte(s[i])
+				default:
+					value.WriteByte('\\')
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return "", fmt.Errorf("unterminated value for label \"%s\"", name)
+		}
+		tags[name] = value.String()
+
+		s = strings.TrimLeft(s[i+1:], " \t")
+		if strings.HasPrefix(s, ",") {
+			s = s[1:]
+		} else if !strings.HasPrefix(s, "}") {
+			return "", errors.New("expected \",\" or \"}\" after label")
+		}
+	}
+}
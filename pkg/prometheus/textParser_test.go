@@ -0,0 +1,61 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseText(t *testing.T) {
+	lines := []string{
+		"# HELP node_load1 1m load average.",
+		"# TYPE node_load1 gauge",
+		"node_load1 0.25",
+		"http_requests_total{method=\"post\",code=\"200\"} 1027 1613985840702",
+		"http_requests_total{ method = \"get\", path=\"C:\\\\dir \\\"x\\\"\\n\", } 3",
+		"",
+		"# TYPE request_duration_seconds histogram",
+		"request_duration_seconds_bucket{le=\"0.5\"} 10",
+		"request_duration_seconds_bucket{le=\"+Inf\"} 12",
+		"request_duration_seconds_sum 4.5e+00",
+		"up{job=\"node\"} NaN",
+		"temperature -Inf",
+	}
+
+	currentTime := time.Now()
+	actual, err := ParseText([]byte(strings.Join(lines, "\n")), currentTime)
+	assert.Nil(t, err)
+
+	expected := []general.Point{
+		{Measurement: "node_load1", Fields: map[string]interface{}{"value": 0.25}, Tags: map[string]string{}, Timestamp: currentTime},
+		{Measurement: "http_requests_total", Fields: map[string]interface{}{"value": 1027.0}, Tags: map[string]string{"method": "post", "code": "200"}, Timestamp: time.Unix(0, 1613985840702*int64(time.Millisecond))},
+		{Measurement: "http_requests_total", Fields: map[string]interface{}{"value": 3.0}, Tags: map[string]string{"method": "get", "path": "C:\\dir \"x\"\n"}, Timestamp: currentTime},
+		{Measurement: "request_duration_seconds_bucket", Fields: map[string]interface{}{"value": 10.0}, Tags: map[string]string{"le": "0.5"}, Timestamp: currentTime},
+		{Measurement: "request_duration_seconds_bucket", Fields: map[string]interface{}{"value": 12.0}, Tags: map[string]string{"le": "+Inf"}, Timestamp: currentTime},
+		{Measurement: "request_duration_seconds_sum", Fields: map[string]interface{}{"value": 4.5}, Tags: map[string]string{}, Timestamp: currentTime},
+	}
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseTextInvalid(t *testing.T) {
+	invalidLines := []string{
+		"node_load1",
+		"{job=\"node\"} 1",
+		"node_load1 abc",
+		"node_load1 1 abc",
+		"node_load1 1 2 3",
+		"node_load1{job=\"node\" 1",
+		"node_load1{job=node} 1",
+		"node_load1{=\"node\"} 1",
+		"node_load1{job=\"node} 1",
+		"node_load1{job=\"node\" x=\"y\"} 1",
+	}
+
+	for _, line := range invalidLines {
+		_, err := ParseText([]byte(line), time.Now())
+		assert.NotNil(t, err, line)
+	}
+}
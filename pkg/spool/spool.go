@@ -0,0 +1,105 @@
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Processor processes the content of a file; errors wrapped in a TemporaryError leave the file in place to be processed again
+// later (e.g. when an output is unavailable), all other errors mark the file as failed
+type Processor func(data []byte) error
+
+// TemporaryError marks an error after which processing should be retried later
+type TemporaryError struct {
+	Err error
+}
+
+func (e TemporaryError) Error() string { return e.Err.Error() }
+func (e TemporaryError) Unwrap() error { return e.Err }
+
+// Directory processes the files that are delivered to a spool directory and moves them to a done or failed directory afterwards
+type Directory struct {
+	directory       string
+	doneDirectory   string
+	failedDirectory string
+	process         Processor
+	log             *logrus.Logger
+}
+
+// NewDirectory creates a spool for directory; processed files are moved to doneDirectory and failedDirectory
+// (by default the subdirectories "done" and "failed"), which are created if they don't exist
+func NewDirectory(directory string, doneDirectory string, failedDirectory string, process Processor, log *logrus.Logger) (*Directory, error) {
+	if doneDirectory == "" {
+		doneDirectory = filepath.Join(directory, "done")
+	}
+	if failedDirectory == "" {
+		failedDirectory = filepath.Join(directory, "failed")
+	}
+
+	info, err := os.Stat(directory)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", directory)
+	}
+	for _, d := range []string{doneDirectory, failedDirectory} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Directory{
+		directory:       directory,
+		doneDirectory:   doneDirectory,
+		failedDirectory: failedDirectory,
+		process:         process,
+		log:             log,
+	}, nil
+}
+
+// Poll processes all files that are currently in the spool directory, in the order of their names. Hidden files are skipped, because
+// tools like rsync write to hidden temporary files and rename them when they are complete
+func (d *Directory) Poll() error {
+	entries, err := ioutil.ReadDir(d.directory)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(d.directory, entry.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		target := d.doneDirectory
+		if err := d.process(data); err != nil {
+			var temporaryError TemporaryError
+			if errors.As(err, &temporaryError) {
+				// keep the file (and the ones after it, to preserve the order) for the next poll
+				return fmt.Errorf("Failed to process %s, retrying later: %w", path, err)
+			}
+			d.log.Warnf("Failed to process %s: %v", path, err)
+			target = d.failedDirectory
+		}
+
+		if err := os.Rename(path, filepath.Join(target, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package spool
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"1.lp":         "valid",
+		"2.lp":         "invalid",
+		"3.lp":         "retry",
+		"4.lp":         "valid",
+		".5.lp.XXXXXX": "valid",
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	var processed []string
+	retry := true
+	process := func(data []byte) error {
+		processed = append(processed, string(data))
+		switch string(data) {
+		case "invalid":
+			return errors.New("invalid")
+		case "retry":
+			if retry {
+				return TemporaryError{errors.New("output unavailable")}
+			}
+		}
+		return nil
+	}
+
+	d, err := NewDirectory(dir, "", "", process, logrus.StandardLogger())
+	assert.Nil(t, err)
+
+	// processing stops at the file that has to be retried
+	assert.NotNil(t, d.Poll())
+	assert.Equal(t, []string{"valid", "invalid", "retry"}, processed)
+	assert.FileExists(t, filepath.Join(dir, "done", "1.lp"))
+	assert.FileExists(t, filepath.Join(dir, "failed", "2.lp"))
+	assert.FileExists(t, filepath.Join(dir, "3.lp"))
+	assert.FileExists(t, filepath.Join(dir, "4.lp"))
+
+	retry = false
+	processed = nil
+	assert.Nil(t, d.Poll())
+	assert.Equal(t, []string{"retry", "valid"}, processed)
+	assert.FileExists(t, filepath.Join(dir, "done", "3.lp"))
+	assert.FileExists(t, filepath.Join(dir, "done", "4.lp"))
+	assert.FileExists(t, filepath.Join(dir, ".5.lp.XXXXXX"))
+}
+
+func TestDirectoryMissing(t *testing.T) {
+	_, err := NewDirectory("/does/not/exist", "", "", func(data []byte) error { return nil }, logrus.StandardLogger())
+	assert.NotNil(t, err)
+}
+
+func TestTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.lp")
+
+	var processed []string
+	var processErr error
+	process := func(data []byte) error {
+		processed = append(processed, string(data))
+		return processErr
+	}
+
+	// a missing file is not an error, it might not have been created yet
+	tail := NewTail(path, "", process)
+	assert.Nil(t, tail.Poll())
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	_, err = f.WriteString("line1\nline2\nincomplete")
+	assert.Nil(t, err)
+	assert.Nil(t, tail.Poll())
+	assert.Equal(t, []string{"line1\nline2\n"}, processed)
+
+	// a new tail continues at the persisted offset
+	processed = nil
+	_, err = f.WriteString(" line3\n")
+	assert.Nil(t, err)
+	tail = NewTail(path, "", process)
+	assert.Nil(t, tail.Poll())
+	assert.Equal(t, []string{"incomplete line3\n"}, processed)
+
+	// temporary errors keep the offset
+	processed = nil
+	processErr = TemporaryError{errors.New("output unavailable")}
+	_, err = f.WriteString("line4\n")
+	assert.Nil(t, err)
+	assert.NotNil(t, tail.Poll())
+	processErr = nil
+	assert.Nil(t, tail.Poll())
+	assert.Equal(t, []string{"line4\n", "line4\n"}, processed)
+
+	// a truncated file is processed from the start
+	processed = nil
+	assert.Nil(t, f.Truncate(0))
+	_, err = f.WriteString("new\n")
+	assert.Nil(t, err)
+	assert.Nil(t, tail.Poll())
+	assert.Equal(t, []string{"new\n"}, processed)
+
+	// a replaced (rotated) file is processed from the start, even if it is larger than the offset
+	processed = nil
+	rotated := filepath.Join(dir, "rotated.lp")
+	assert.Nil(t, ioutil.WriteFile(rotated, []byte("rotated1\nrotated2\n"), 0644))
+	assert.Nil(t, os.Rename(rotated, path))
+	assert.Nil(t, tail.Poll())
+	assert.Equal(t, []string{"rotated1\nrotated2\n"}, processed)
+
+	// a line exceeding the chunk size is skipped once it is complete
+	processed = nil
+	f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	defer f.Close()
+	_, err = f.WriteString(strings.Repeat("x", maxChunkSize+10))
+	assert.Nil(t, err)
+	assert.Nil(t, tail.Poll())
+	_, err = f.WriteString("\nafter\n")
+	assert.Nil(t, err)
+	assert.NotNil(t, tail.Poll())
+	assert.Nil(t, tail.Poll())
+	assert.Equal(t, []string{"after\n"}, processed)
+}
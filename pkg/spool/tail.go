@@ -0,0 +1,162 @@
+package spool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maximum number of bytes passed to the processor at once
+const maxChunkSize = 4 * 1024 * 1024
+
+// Tail processes the lines that are appended to a growing file; the offset up to which the file was processed is persisted
+// in an offset file, so that a restart continues where it stopped
+type Tail struct {
+	path       string
+	offsetPath string
+	process    Processor
+	file       os.FileInfo // the file of the last poll, to detect that it was replaced
+}
+
+// NewTail creates a tail for path; the offset is stored in offsetPath (by default path + ".offset")
+func NewTail(path string, offsetPath string, process Processor) *Tail {
+	if offsetPath == "" {
+		offsetPath = path + ".offset"
+	}
+	return &Tail{path: path, offsetPath: offsetPath, process: process}
+}
+
+// Poll processes the complete lines appended since the last poll; an incomplete last line is left for the next poll.
+// If the file is smaller than the stored offset or is another file than at the last poll, it was truncated or replaced
+// (rotated) and is processed from the start. Lines whose processing failed with a non-temporary error and lines longer
+// than maxChunkSize are skipped
+func (t *Tail) Poll() error {
+	offset, err := t.readOffset()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < offset || (t.file != nil && !os.SameFile(t.file, info)) {
+		offset = 0
+		if err := t.writeOffset(offset); err != nil {
+			return err
+		}
+	}
+	t.file = info
+
+	buf := make([]byte, maxChunkSize)
+	for offset < info.Size() {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		end := bytes.LastIndexByte(buf[:n], '\n')
+		if end < 0 {
+			if n < len(buf) {
+				// the last line is not complete yet
+				return nil
+			}
+			// the line is skipped once it is complete, otherwise the tail would stop at it
+			lineEnd, err := findLineEnd(f, offset+int64(n), buf)
+			if err != nil || lineEnd < 0 {
+				return err
+			}
+			err = fmt.Errorf("Skipped line at offset %d of %s, it exceeds %d bytes", offset, t.path, maxChunkSize)
+			offset = lineEnd + 1
+			if writeErr := t.writeOffset(offset); writeErr != nil {
+				return writeErr
+			}
+			return err
+		}
+
+		if err := t.process(buf[:end+1]); err != nil {
+			var temporaryError TemporaryError
+			if errors.As(err, &temporaryError) {
+				return fmt.Errorf("Failed to process %s at offset %d, retrying later: %w", t.path, offset, err)
+			}
+			// the chunk is skipped, otherwise the same invalid data would be processed again and again
+			err = fmt.Errorf("Failed to process %s at offset %d: %w", t.path, offset, err)
+			offset += int64(end + 1)
+			if writeErr := t.writeOffset(offset); writeErr != nil {
+				return writeErr
+			}
+			return err
+		}
+
+		offset += int64(end + 1)
+		if err := t.writeOffset(offset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findLineEnd returns the offset of the first newline at or after offset, or -1 if there is none
+func findLineEnd(f *os.File, offset int64, buf []byte) (int64, error) {
+	for {
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return offset + int64(i), nil
+		}
+		if n < len(buf) {
+			return -1, nil
+		}
+		offset += int64(n)
+	}
+}
+
+func (t *Tail) readOffset() (int64, error) {
+	data, err := ioutil.ReadFile(t.offsetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid offset file %s: %w", t.offsetPath, err)
+	}
+	return offset, nil
+}
+
+// writeOffset replaces the offset file atomically, so that a crash can't leave a corrupt offset behind
+func (t *Tail) writeOffset(offset int64) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(t.offsetPath), filepath.Base(t.offsetPath)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(strconv.FormatInt(offset, 10))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), t.offsetPath)
+}
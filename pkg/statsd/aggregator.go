@@ -0,0 +1,243 @@
+package statsd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+)
+
+// name of the tag that carries the statsd metric type of the emitted points
+const metricTypeTag = "metric_type"
+
+// Aggregator collects statsd samples and emits aggregated points on Flush
+type Aggregator struct {
+	percentiles  []float64
+	deleteGauges bool
+
+	lock     sync.Mutex
+	counters map[string]*counterValue
+	gauges   map[string]*gaugeValue
+	timers   map[string]*timerValue
+	sets     map[string]*setValue
+}
+
+type series struct {
+	name string
+	tags map[string]string
+}
+
+type counterValue struct {
+	series
+	value float64
+}
+
+type gaugeValue struct {
+	series
+	value float64
+}
+
+type timerValue struct {
+	series
+	values []float64
+	count  float64 // count extrapolated by the sample rates
+}
+
+type setValue struct {
+	series
+	values map[string]struct{}
+}
+
+// NewAggregator creates an aggregator; percentiles (e.g. 90, 99) are calculated for timers. Gauges keep their value across
+// flushes (like statsd does) unless deleteGauges is set
+func NewAggregator(percentiles []float64, deleteGauges bool) (*Aggregator, error) {
+	for _, p := range percentiles {
+		if p <= 0 || p > 100 {
+			return nil, fmt.Errorf("Invalid percentile %v, must be in (0, 100]", p)
+		}
+	}
+
+	a := &Aggregator{
+		percentiles:  percentiles,
+		deleteGauges: deleteGauges,
+	}
+	a.reset()
+	a.gauges = make(map[string]*gaugeValue)
+	return a, nil
+}
+
+func (a *Aggregator) reset() {
+	a.counters = make(map[string]*counterValue)
+	a.timers = make(map[string]*timerValue)
+	a.sets = make(map[string]*setValue)
+	if a.deleteGauges {
+		a.gauges = make(map[string]*gaugeValue)
+	}
+}
+
+// Add parses the newline separated statsd lines in data and adds them to the aggregation;
+// it returns the number of accepted lines and the errors of the rejected lines
+func (a *Aggregator) Add(data []byte) (int, []error) {
+	var samples []sample
+	var errs []error
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		s, err := parseLine(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid line \"%s\": %w", line, err))
+			continue
+		}
+		samples = append(samples, s)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, s := range samples {
+		a.add(s)
+	}
+
+	return len(samples), errs
+}
+
+func (a *Aggregator) add(s sample) {
+	key := seriesKey(s.name, s.tags)
+
+	switch s.metricType {
+	case counter:
+		c, ok := a.counters[key]
+		if !ok {
+			c = &counterValue{series: series{s.name, s.tags}}
+			a.counters[key] = c
+		}
+		c.value += s.value / s.sampleRate
+	case gauge:
+		g, ok := a.gauges[key]
+		if !ok {
+			g = &gaugeValue{series: series{s.name, s.tags}}
+			a.gauges[key] = g
+		}
+		if s.relative {
+			g.value += s.value
+		} else {
+			g.value = s.value
+		}
+	case timer:
+		t, ok := a.timers[key]
+		if !ok {
+			t = &timerValue{series: series{s.name, s.tags}}
+			a.timers[key] = t
+		}
+		t.values = append(t.values, s.value)
+		t.count += 1 / s.sampleRate
+	case set:
+		st, ok := a.sets[key]
+		if !ok {
+			st = &setValue{series: series{s.name, s.tags}, values: make(map[string]struct{})}
+			a.sets[key] = st
+		}
+		st.values[s.setValue] = struct{}{}
+	}
+}
+
+// seriesKey identifies a series by its name and (sorted) tags
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, k := range keys {
+		sb.WriteString("\x00")
+		sb.WriteString(k)
+		sb.WriteString("\x00")
+		sb.WriteString(tags[k])
+	}
+	return sb.String()
+}
+
+// Flush emits one point per series with the aggregated values since the last flush and resets the aggregation
+func (a *Aggregator) Flush(now time.Time) []general.Point {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var ret []general.Point
+	for _, c := range a.counters {
+		ret = append(ret, newPoint(c.series, counter, map[string]interface{}{"value": c.value}, now))
+	}
+	for _, g := range a.gauges {
+		ret = append(ret, newPoint(g.series, gauge, map[string]interface{}{"value": g.value}, now))
+	}
+	for _, t := range a.timers {
+		ret = append(ret, newPoint(t.series, timer, a.timerFields(t), now))
+	}
+	for _, s := range a.sets {
+		ret = append(ret, newPoint(s.series, set, map[string]interface{}{"value": int64(len(s.values))}, now))
+	}
+
+	a.reset()
+
+	return ret
+}
+
+func newPoint(s series, t metricType, fields map[string]interface{}, now time.Time) general.Point {
+	tags := make(map[string]string, len(s.tags)+1)
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	tags[metricTypeTag] = string(t)
+
+	return general.Point{Measurement: s.name, Fields: fields, Tags: tags, Timestamp: now}
+}
+
+func (a *Aggregator) timerFields(t *timerValue) map[string]interface{} {
+	values := t.values
+	sort.Float64s(values)
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	fields := map[string]interface{}{
+		"count":  t.count,
+		"sum":    sum,
+		"mean":   mean,
+		"lower":  values[0],
+		"upper":  values[len(values)-1],
+		"median": percentile(values, 50),
+		"stddev": math.Sqrt(variance / float64(len(values))),
+	}
+	for _, p := range a.percentiles {
+		fields["p"+strconv.FormatFloat(p, 'f', -1, 64)] = percentile(values, p)
+	}
+	return fields
+}
+
+// percentile calculates the nearest-rank percentile of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
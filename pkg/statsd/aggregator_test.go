@@ -0,0 +1,75 @@
+package statsd
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/max-bytes/metrics-receiver/pkg/general"
+	"github.com/stretchr/testify/assert"
+)
+
+func sortPoints(points []general.Point) {
+	sort.Slice(points, func(i, j int) bool {
+		return seriesKey(points[i].Measurement, points[i].Tags) < seriesKey(points[j].Measurement, points[j].Tags)
+	})
+}
+
+func TestAggregator(t *testing.T) {
+	a, err := NewAggregator([]float64{90}, false)
+	assert.Nil(t, err)
+
+	accepted, errs := a.Add([]byte("requests:1|c\nrequests:1|c|@0.5\nrequests:1|c|#host:a\ninvalid\n"))
+	assert.Equal(t, 3, accepted)
+	assert.Len(t, errs, 1)
+
+	_, errs = a.Add([]byte("temperature:20|g\ntemperature:+2|g\nusers:alice|s\nusers:bob|s\nusers:alice|s"))
+	assert.Empty(t, errs)
+
+	timerLines := ""
+	for i := 1; i <= 10; i++ {
+		timerLines += "response_time:" + string(rune('0'+i%10)) + "|ms\n"
+	}
+	_, errs = a.Add([]byte(timerLines))
+	assert.Empty(t, errs)
+
+	now := time.Now()
+	actual := a.Flush(now)
+	sortPoints(actual)
+
+	expected := []general.Point{
+		{Measurement: "requests", Fields: map[string]interface{}{"value": 1.0}, Tags: map[string]string{"host": "a", "metric_type": "counter"}, Timestamp: now},
+		{Measurement: "requests", Fields: map[string]interface{}{"value": 3.0}, Tags: map[string]string{"metric_type": "counter"}, Timestamp: now},
+		{Measurement: "response_time", Fields: map[string]interface{}{
+			"count": 10.0, "sum": 45.0, "mean": 4.5, "lower": 0.0, "upper": 9.0, "median": 4.0, "stddev": 2.8722813232690143, "p90": 8.0,
+		}, Tags: map[string]string{"metric_type": "timer"}, Timestamp: now},
+		{Measurement: "temperature", Fields: map[string]interface{}{"value": 22.0}, Tags: map[string]string{"metric_type": "gauge"}, Timestamp: now},
+		{Measurement: "users", Fields: map[string]interface{}{"value": int64(2)}, Tags: map[string]string{"metric_type": "set"}, Timestamp: now},
+	}
+	assert.Equal(t, expected, actual)
+
+	// only gauges survive a flush
+	actual = a.Flush(now)
+	assert.Equal(t, []general.Point{
+		{Measurement: "temperature", Fields: map[string]interface{}{"value": 22.0}, Tags: map[string]string{"metric_type": "gauge"}, Timestamp: now},
+	}, actual)
+}
+
+func TestAggregatorDeleteGauges(t *testing.T) {
+	a, err := NewAggregator(nil, true)
+	assert.Nil(t, err)
+
+	_, errs := a.Add([]byte("temperature:20|g"))
+	assert.Empty(t, errs)
+
+	assert.Len(t, a.Flush(time.Now()), 1)
+	assert.Len(t, a.Flush(time.Now()), 0)
+}
+
+func TestAggregatorInvalidPercentile(t *testing.T) {
+	_, err := NewAggregator([]float64{0}, false)
+	assert.NotNil(t, err)
+
+	_, err = NewAggregator([]float64{101}, false)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,107 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type metricType string
+
+const (
+	counter metricType = "counter"
+	gauge   metricType = "gauge"
+	timer   metricType = "timer"
+	set     metricType = "set"
+)
+
+// sample is a single parsed statsd line
+type sample struct {
+	name       string
+	metricType metricType
+	tags       map[string]string
+
+	value      float64
+	setValue   string  // only for sets
+	relative   bool    // gauges with explicit sign modify the current value instead of replacing it
+	sampleRate float64 // only for counters and timers, 1 means not sampled
+}
+
+// parseLine parses a line "name:value|type[|@sample_rate][|#tag1:value1,tag2]" (DogStatsD style tags)
+func parseLine(line string) (sample, error) {
+	colon := strings.LastIndex(line, ":")
+	pipe := strings.Index(line, "|")
+	// the metric name may contain colons, but only before the value
+	for colon > pipe && pipe >= 0 {
+		colon = strings.LastIndex(line[:colon], ":")
+	}
+	if colon <= 0 || pipe < 0 {
+		return sample{}, errors.New("expected \"name:value|type\"")
+	}
+
+	s := sample{name: line[:colon], tags: make(map[string]string), sampleRate: 1}
+	valueStr := line[colon+1 : pipe]
+	sections := strings.Split(line[pipe+1:], "|")
+
+	switch sections[0] {
+	case "c":
+		s.metricType = counter
+	case "g":
+		s.metricType = gauge
+	case "ms", "h", "d":
+		s.metricType = timer
+	case "s":
+		s.metricType = set
+	default:
+		return sample{}, fmt.Errorf("unknown metric type \"%s\"", sections[0])
+	}
+
+	for _, section := range sections[1:] {
+		switch {
+		case strings.HasPrefix(section, "@"):
+			rate, err := strconv.ParseFloat(section[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return sample{}, fmt.Errorf("invalid sample rate \"%s\"", section[1:])
+			}
+			if s.metricType == counter || s.metricType == timer {
+				s.sampleRate = rate
+			}
+		case strings.HasPrefix(section, "#"):
+			for _, tag := range strings.Split(section[1:], ",") {
+				if tag == "" {
+					continue
+				}
+				kv := strings.SplitN(tag, ":", 2)
+				if len(kv) == 2 && kv[1] != "" {
+					s.tags[kv[0]] = kv[1]
+				} else {
+					// tags without value can not be represented as tag with empty value
+					s.tags[kv[0]] = "true"
+				}
+			}
+		default:
+			return sample{}, fmt.Errorf("invalid section \"%s\"", section)
+		}
+	}
+
+	if s.metricType == set {
+		if valueStr == "" {
+			return sample{}, errors.New("missing value")
+		}
+		s.setValue = valueStr
+		return s, nil
+	}
+
+	if s.metricType == gauge && (strings.HasPrefix(valueStr, "+") || strings.HasPrefix(valueStr, "-")) {
+		s.relative = true
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return sample{}, fmt.Errorf("invalid value \"%s\"", valueStr)
+	}
+	s.value = value
+
+	return s, nil
+}
@@ -0,0 +1,45 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := map[string]sample{
+		"requests:1|c":                        {name: "requests", metricType: counter, tags: map[string]string{}, value: 1, sampleRate: 1},
+		"requests:2|c|@0.5":                   {name: "requests", metricType: counter, tags: map[string]string{}, value: 2, sampleRate: 0.5},
+		"temperature:-12.5|g":                 {name: "temperature", metricType: gauge, tags: map[string]string{}, value: -12.5, relative: true, sampleRate: 1},
+		"temperature:12.5|g|@0.5":             {name: "temperature", metricType: gauge, tags: map[string]string{}, value: 12.5, sampleRate: 1},
+		"response_time:320|ms|#host:a,canary": {name: "response_time", metricType: timer, tags: map[string]string{"host": "a", "canary": "true"}, value: 320, sampleRate: 1},
+		"size:12|h|@0.1|#env:prod":            {name: "size", metricType: timer, tags: map[string]string{"env": "prod"}, value: 12, sampleRate: 0.1},
+		"users:alice|s":                       {name: "users", metricType: set, tags: map[string]string{}, setValue: "alice", sampleRate: 1},
+		"a:b:1|c|#url:http://x":               {name: "a:b", metricType: counter, tags: map[string]string{"url": "http://x"}, value: 1, sampleRate: 1},
+	}
+
+	for line, expected := range tests {
+		actual, err := parseLine(line)
+		assert.Nil(t, err, line)
+		assert.Equal(t, expected, actual, line)
+	}
+}
+
+func TestParseInvalidLine(t *testing.T) {
+	invalidLines := []string{
+		"requests",
+		"requests:1",
+		":1|c",
+		"requests:1|x",
+		"requests:abc|c",
+		"requests:NaN|g",
+		"requests:1|c|@2",
+		"requests:1|c|foo",
+		"users:|s",
+	}
+
+	for _, line := range invalidLines {
+		_, err := parseLine(line)
+		assert.NotNil(t, err, line)
+	}
+}
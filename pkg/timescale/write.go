@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/jackc/pgx"
@@ -96,11 +97,12 @@ func buildDBRowsTimescale(i []general.PointGroup, cfg *config.OutputTimescale, e
 				}
 			}
 
+			// in the data column, json keeps unsigned integers exact
 			var fields = point.Fields
 			var fieldColumnValues []interface{}
 			for _, v := range fieldsAsColumns {
 				if _, ok := fields[v]; ok {
-					fieldColumnValues = append(fieldColumnValues, fields[v])
+					fieldColumnValues = append(fieldColumnValues, convertColumnValue(fields[v]))
 				} else {
 					fieldColumnValues = append(fieldColumnValues, nil)
 				}
@@ -137,6 +139,20 @@ func buildDBRowsTimescale(i []general.PointGroup, cfg *config.OutputTimescale, e
 	return rows, nil
 }
 
+// convertColumnValue converts unsigned integer fields, because pgx can't copy uint64 values above math.MaxInt64 into bigint
+// columns or uint64 values that aren't exactly representable into double precision columns:
+// they become signed integers if they fit, otherwise floats (losing precision)
+func convertColumnValue(v interface{}) interface{} {
+	u, ok := v.(uint64)
+	if !ok {
+		return v
+	}
+	if u <= math.MaxInt64 {
+		return int64(u)
+	}
+	return float64(u)
+}
+
 func contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgtype"
 	"github.com/max-bytes/metrics-receiver/pkg/config"
 	"github.com/max-bytes/metrics-receiver/pkg/general"
 	"github.com/sirupsen/logrus"
@@ -99,6 +100,95 @@ func TestBuildDBRowsTimescale(t *testing.T) {
 	assert.Equal(t, expected, rows)
 }
 
+func TestBuildDBRowsTimescaleRenamedMeasurement(t *testing.T) {
+
+	t1 := time.Now()
+
+	pointGroups := []general.PointGroup{
+		{Measurement: "cpu", Points: []general.Point{
+			{Measurement: "cpu", Fields: map[string]interface{}{"val": 1.5}, Tags: map[string]string{"hostname": "host_value"}, Timestamp: t1},
+		}},
+	}
+	cfg := config.OutputTimescale{
+		Measurements: map[string]config.MeasurementTimescale{
+			"cpu": {
+				Processors:      []config.ProcessorConfig{{Type: "rename", Tags: map[string]string{"hostname": "host"}, Fields: map[string]string{"val": "value"}, Measurement: "cpu_usage"}},
+				FieldsAsColumns: []string{"val"},
+				TargetTable:     "cpu",
+			},
+			"cpu_usage": {
+				FieldsAsColumns: []string{"value"},
+				TagsAsColumns:   []string{"host"},
+				TargetTable:     "cpu_usage",
+			},
+		},
+	}
+
+	preparedPointGroups, err := general.PreparePointGroups(pointGroups, &cfg, []config.EnrichmentSet{}, logrus.StandardLogger())
+	assert.Nil(t, err)
+
+	rows, err := buildDBRowsTimescale(preparedPointGroups, &cfg, nil)
+	assert.Nil(t, err)
+
+	// the renamed points are written to the target table and columns of the new measurement
+	expected := []TimescaleRows{
+		{
+			InsertColumns: []string{"time", "data", "value", "host"},
+			InsertRows:    [][]interface{}{{t1, []byte(`{}`), 1.5, "host_value"}},
+			TargetTable:   "cpu_usage",
+		},
+	}
+	assert.Equal(t, expected, rows)
+}
+
+func TestBuildDBRowsTimescaleValueTypes(t *testing.T) {
+
+	t1 := time.Now()
+
+	pointGroups := []general.PointGroup{
+		{Measurement: "metric", Points: []general.Point{
+			{Measurement: "metric", Fields: map[string]interface{}{"value": uint64(18446744073709551615), "counter": uint64(12), "up": true, "total": uint64(18446744073709551615)}, Tags: map[string]string{"host": "host_value"}, Timestamp: t1},
+		}},
+	}
+	cfg := config.OutputTimescale{
+		Measurements: map[string]config.MeasurementTimescale{
+			"metric": {
+				FieldsAsColumns: []string{"value", "counter", "up"},
+				TagsAsColumns:   []string{"host"},
+				TargetTable:     "metric",
+			},
+		},
+	}
+
+	rows, err := buildDBRowsTimescale(pointGroups, &cfg, nil)
+	assert.Nil(t, err)
+	row := rows[0].InsertRows[0]
+
+	// the data column keeps unsigned integers exact
+	assert.Equal(t, []byte(`{"total":18446744073709551615}`), row[1])
+
+	// the column values can be encoded by pgx: a counter above math.MaxInt64 as double precision, smaller counters as bigint
+	var float8Value pgtype.Float8
+	assert.Nil(t, float8Value.Set(row[2]))
+	_, err = float8Value.EncodeBinary(nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(18446744073709551615), float8Value.Float)
+
+	var int8Value pgtype.Int8
+	assert.Nil(t, int8Value.Set(row[3]))
+	_, err = int8Value.EncodeBinary(nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), int8Value.Int)
+
+	var boolValue pgtype.Bool
+	assert.Nil(t, boolValue.Set(row[4]))
+	assert.True(t, boolValue.Bool)
+
+	// uint64 values are not encodable as such
+	assert.NotNil(t, int8Value.Set(uint64(18446744073709551615)))
+	assert.NotNil(t, float8Value.Set(uint64(18446744073709551615)))
+}
+
 func BenchmarkBuildDBRowsTimescale(b *testing.B) {
 
 	t1 := time.Now()